@@ -0,0 +1,173 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/chenwei791129/multiablo/internal/config"
+	"github.com/chenwei791129/multiablo/internal/engine"
+	"github.com/chenwei791129/multiablo/internal/game"
+	"github.com/chenwei791129/multiablo/internal/handle"
+	"github.com/chenwei791129/multiablo/internal/i18n"
+	"github.com/chenwei791129/multiablo/internal/process"
+)
+
+// Exit codes returned by the command-line interface
+const (
+	exitOK       = 0
+	exitFailure  = 1
+	exitUsage    = 2
+	exitNotFound = 3
+)
+
+const usageText = `Usage: multiablo [command] [flags]
+
+Commands:
+  gui                 Start the graphical interface (default)
+  watch               Monitor continuously without a window
+  scan                List game processes and their single-instance handles
+  close --pid N       Close the single-instance handles of one game process
+  handles --pid N     List the handles of any process (filter by type or name)
+  agent kill          Terminate all launcher helper processes such as Agent.exe
+  agent relaunch      Start Agent.exe again
+
+Run "multiablo <command> -h" for the flags of a command.
+
+watch, close and agent accept --dry-run to report what would be closed,
+terminated or launched without changing anything.
+
+Settings are read from config.json in the multiablo folder of the user
+configuration directory (%AppData%\multiablo\config.json on Windows).
+Additional game definitions are read from the games folder next to it.
+
+Exit codes:
+  0  success
+  1  the operation failed
+  2  invalid command line
+  3  nothing to do (no matching process or handle)
+`
+
+// run dispatches the command line and returns the process exit code
+func run(args []string) int {
+	if len(args) == 0 {
+		return runGUI(nil)
+	}
+
+	command, rest := args[0], args[1:]
+	if command == "gui" {
+		return runGUI(rest)
+	}
+
+	// Every other command writes to the console
+	attachConsole()
+
+	var handler func(settings, []string) int
+	switch command {
+	case "watch":
+		handler = runWatch
+	case "scan":
+		handler = runScan
+	case "close":
+		handler = runClose
+	case "handles":
+		handler = runHandles
+	case "agent":
+		handler = runAgent
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(os.Stdout, usageText)
+		return exitOK
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n", command)
+		fmt.Fprint(os.Stderr, usageText)
+		return exitUsage
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return exitFailure
+	}
+	i18n.Init(cfg.Language)
+
+	games, err := loadGames()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return exitFailure
+	}
+
+	return handler(settings{cfg: cfg, games: games}, rest)
+}
+
+// settings holds what the console commands read from the settings files
+type settings struct {
+	cfg   config.Config
+	games *game.Registry
+}
+
+// newEngine creates an engine for the settings, in observe-only mode if dryRun is set
+func (s settings) newEngine(dryRun bool) *engine.Engine {
+	return engine.New(engine.Options{Config: &s.cfg, Games: s.games, DryRun: dryRun})
+}
+
+// loadConfig reads the settings file from its default location
+func loadConfig() (config.Config, error) {
+	path, err := config.DefaultPath()
+	if err != nil {
+		return config.Default(), err
+	}
+	return config.Load(path)
+}
+
+// loadGames reads the built-in and user game definitions
+func loadGames() (*game.Registry, error) {
+	path, err := config.DefaultPath()
+	if err != nil {
+		return game.BuiltinRegistry(), err
+	}
+	return game.Load(config.GamesDir(path))
+}
+
+// newFlagSet creates a flag set for a command that reports errors instead of exiting
+func newFlagSet(name, summary string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: multiablo %s [flags]\n\n%s\n\nFlags:\n", name, summary)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses command flags and returns the exit code to use
+// when parsing did not succeed
+func parseFlags(fs *flag.FlagSet, args []string) (int, bool) {
+	err := fs.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK, false
+	}
+	if err != nil {
+		return exitUsage, false
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(fs.Output(), "unexpected argument: %s\n", fs.Arg(0))
+		fs.Usage()
+		return exitUsage, false
+	}
+	return exitOK, true
+}
+
+// exitCodeFor reports an error and maps it to an exit code.
+// ErrDryRun only means that an action was reported instead of carried out.
+func exitCodeFor(w io.Writer, err error) int {
+	if errors.Is(err, engine.ErrDryRun) {
+		return exitOK
+	}
+	fmt.Fprintf(w, "error: %v\n", err)
+	if errors.Is(err, handle.ErrNoHandles) || errors.Is(err, process.ErrNoProcess) {
+		return exitNotFound
+	}
+	return exitFailure
+}
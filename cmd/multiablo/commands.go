@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"regexp"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/chenwei791129/multiablo/internal/config"
+	"github.com/chenwei791129/multiablo/internal/engine"
+	"github.com/chenwei791129/multiablo/internal/gui"
+	"github.com/chenwei791129/multiablo/internal/handle"
+	"github.com/chenwei791129/multiablo/internal/i18n"
+)
+
+// runGUI starts the graphical interface
+func runGUI(args []string) int {
+	fs := newFlagSet("gui", "Start the graphical interface.")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	// Without a usable settings location the GUI runs with the defaults
+	path, _ := config.DefaultPath()
+
+	app := gui.NewApp(path)
+	app.Run()
+	return exitOK
+}
+
+// runWatch runs the monitoring engine without a window until interrupted
+func runWatch(s settings, args []string) int {
+	fs := newFlagSet("watch", "Monitor the processes of the enabled games and their launcher helpers,\nsuch as D2R.exe and Agent.exe, continuously without a window.\nStops on Ctrl+C.")
+	jsonOut := fs.Bool("json", false, "print events as JSON lines")
+	dryRun := fs.Bool("dry-run", false, "only report which handles would be closed and which processes would be terminated or launched")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	eng := s.newEngine(*dryRun)
+	events, cancel := eng.Subscribe()
+	defer cancel()
+
+	if err := eng.Start(); err != nil {
+		return exitCodeFor(os.Stderr, err)
+	}
+	defer eng.Stop()
+
+	out := newEventWriter(os.Stdout, *jsonOut)
+	if !*jsonOut {
+		fmt.Fprintln(os.Stdout, i18n.Get("Monitoring started..."))
+		if *dryRun {
+			fmt.Fprintln(os.Stdout, i18n.Get("Observe-only mode on: handles and processes are reported but not changed"))
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			if !*jsonOut {
+				fmt.Fprintln(os.Stdout, i18n.Get("Monitoring stopped."))
+			}
+			return exitOK
+		case event := <-events:
+			out.write(event)
+		}
+	}
+}
+
+// scanHandle is the JSON representation of a single-instance handle
+type scanHandle struct {
+	Handle uintptr `json:"handle"`
+	Type   string  `json:"type"`
+	Name   string  `json:"name"`
+}
+
+// scanInstance is the JSON representation of a scanned game process
+type scanInstance struct {
+	PID         uint32       `json:"pid"`
+	ProcessName string       `json:"process_name"`
+	Game        string       `json:"game"`
+	Handles     []scanHandle `json:"handles"`
+	Error       string       `json:"error,omitempty"`
+}
+
+// runScan lists the processes of the enabled games and their single-instance handles once
+func runScan(s settings, args []string) int {
+	fs := newFlagSet("scan", "List the processes of the enabled games and their single-instance handles\nwithout closing them.")
+	jsonOut := fs.Bool("json", false, "print the result as JSON")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	eng := s.newEngine(false)
+	reports, err := eng.Scan()
+	if err != nil {
+		return exitCodeFor(os.Stderr, err)
+	}
+
+	code := exitOK
+	if len(reports) == 0 {
+		code = exitNotFound
+	}
+
+	instances := make([]scanInstance, 0, len(reports))
+	for _, r := range reports {
+		inst := scanInstance{PID: r.PID, ProcessName: r.ProcessName, Game: r.Game, Handles: []scanHandle{}}
+		for _, h := range r.Handles {
+			inst.Handles = append(inst.Handles, scanHandle{Handle: h.Handle, Type: h.TypeName, Name: h.Name})
+		}
+		if r.Err != nil {
+			inst.Error = r.Err.Error()
+			code = exitFailure
+		}
+		instances = append(instances, inst)
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(instances)
+		return code
+	}
+
+	if len(instances) == 0 {
+		fmt.Fprintln(os.Stdout, i18n.Get("No game processes detected"))
+		return code
+	}
+	for _, inst := range instances {
+		if inst.Error != "" {
+			fmt.Fprintf(os.Stdout, "%s PID %d: error: %s\n", inst.ProcessName, inst.PID, inst.Error)
+			continue
+		}
+		fmt.Fprintf(os.Stdout, "%s PID %d: %d single-instance handle(s)\n", inst.ProcessName, inst.PID, len(inst.Handles))
+		for _, h := range inst.Handles {
+			fmt.Fprintf(os.Stdout, "  0x%X  %s  %s\n", h.Handle, h.Type, h.Name)
+		}
+	}
+	return code
+}
+
+// runClose closes the single-instance handles of one game process
+func runClose(s settings, args []string) int {
+	fs := newFlagSet("close", "Close the single-instance handles of one process of an enabled game.")
+	pid := fs.Uint("pid", 0, "process ID of the game instance, e.g. of D2R.exe (required)")
+	jsonOut := fs.Bool("json", false, "print events as JSON lines")
+	dryRun := fs.Bool("dry-run", false, "only report the handles that would be closed")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if *pid == 0 || uint64(*pid) > uint64(^uint32(0)) {
+		fmt.Fprintln(os.Stderr, "a valid --pid is required")
+		fs.Usage()
+		return exitUsage
+	}
+
+	eng := s.newEngine(*dryRun)
+	events, cancel := eng.Subscribe()
+
+	_, err := eng.CloseHandles(uint32(*pid))
+
+	cancel()
+	newEventWriter(os.Stdout, *jsonOut).drain(events)
+
+	if err != nil {
+		return exitCodeFor(os.Stderr, err)
+	}
+	return exitOK
+}
+
+// runHandles lists the handles of any process for debugging
+func runHandles(s settings, args []string) int {
+	fs := newFlagSet("handles", "List the handles of a process with their type, name and granted access.\nNames are resolved for named object types such as Event, Mutant and Section.")
+	pid := fs.Uint("pid", 0, "process ID to inspect (required)")
+	typeName := fs.String("type", "", "only list handles of this object type, e.g. Event, Mutant or Section")
+	nameRegex := fs.String("name-regex", "", "only list handles whose name matches this regular expression")
+	jsonOut := fs.Bool("json", false, "print the handles as JSON")
+	csvOut := fs.Bool("csv", false, "print the handles as CSV")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if *pid == 0 || uint64(*pid) > uint64(^uint32(0)) {
+		fmt.Fprintln(os.Stderr, "a valid --pid is required")
+		fs.Usage()
+		return exitUsage
+	}
+	if *jsonOut && *csvOut {
+		fmt.Fprintln(os.Stderr, "--json and --csv cannot be combined")
+		return exitUsage
+	}
+
+	filter := handle.Filter{TypeName: *typeName}
+	if *nameRegex != "" {
+		re, err := regexp.Compile(*nameRegex)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid --name-regex: %v\n", err)
+			return exitUsage
+		}
+		filter.NameRegex = re
+	}
+
+	eng := s.newEngine(false)
+	handles, err := eng.InspectHandles(uint32(*pid), filter)
+	if err != nil {
+		return exitCodeFor(os.Stderr, err)
+	}
+
+	code := exitOK
+	if len(handles) == 0 {
+		code = exitNotFound
+	}
+
+	switch {
+	case *jsonOut:
+		_ = handle.WriteJSON(os.Stdout, handles)
+	case *csvOut:
+		_ = handle.WriteCSV(os.Stdout, handles)
+	default:
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "HANDLE\tTYPE\tACCESS\tNAME")
+		for _, h := range handles {
+			fmt.Fprintf(tw, "0x%X\t%s\t0x%08X\t%s\n", h.Handle, h.TypeName, h.GrantedAccess, h.Name)
+		}
+		_ = tw.Flush()
+		fmt.Fprintf(os.Stdout, "%d handle(s)\n", len(handles))
+	}
+	return code
+}
+
+// runAgent terminates or relaunches the launcher helpers such as Agent.exe
+func runAgent(s settings, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, "Usage: multiablo agent kill|relaunch [flags]\n")
+		return exitUsage
+	}
+
+	switch args[0] {
+	case "kill":
+		return runAgentKill(s, args[1:])
+	case "relaunch":
+		return runAgentRelaunch(s, args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown agent command: %s\n", args[0])
+		fmt.Fprint(os.Stderr, "Usage: multiablo agent kill|relaunch [flags]\n")
+		return exitUsage
+	}
+}
+
+// runAgentKill terminates all launcher helper processes
+func runAgentKill(s settings, args []string) int {
+	fs := newFlagSet("agent kill", "Terminate all launcher helper processes of the enabled games, such as Agent.exe.")
+	jsonOut := fs.Bool("json", false, "print events as JSON lines")
+	dryRun := fs.Bool("dry-run", false, "only report the processes that would be terminated")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	eng := s.newEngine(*dryRun)
+	events, cancel := eng.Subscribe()
+
+	_, _, err := eng.KillAgents()
+
+	cancel()
+	newEventWriter(os.Stdout, *jsonOut).drain(events)
+
+	if err != nil {
+		return exitCodeFor(os.Stderr, err)
+	}
+	return exitOK
+}
+
+// runAgentRelaunch starts Agent.exe
+func runAgentRelaunch(s settings, args []string) int {
+	fs := newFlagSet("agent relaunch", "Start Agent.exe. By default it is started from the path of a\nrunning Agent.exe, or from the agent_path setting.")
+	path := fs.String("path", "", "path to Agent.exe")
+	jsonOut := fs.Bool("json", false, "print events as JSON lines")
+	dryRun := fs.Bool("dry-run", false, "only report the path Agent.exe would be started from")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	eng := s.newEngine(*dryRun)
+	agentPath := *path
+	if agentPath == "" {
+		agentPath = eng.AgentPath()
+	}
+
+	events, cancel := eng.Subscribe()
+
+	_, err := eng.RelaunchAgent(agentPath)
+
+	cancel()
+	newEventWriter(os.Stdout, *jsonOut).drain(events)
+
+	if err != nil && !errors.Is(err, engine.ErrDryRun) {
+		// The failure was already reported as an event
+		return exitFailure
+	}
+	return exitOK
+}
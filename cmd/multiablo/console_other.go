@@ -0,0 +1,6 @@
+//go:build !windows
+
+package main
+
+// attachConsole is a no-op on non-Windows systems, where the standard streams are always connected
+func attachConsole() {}
@@ -0,0 +1,40 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// attachParentProcess is the ATTACH_PARENT_PROCESS argument of AttachConsole
+const attachParentProcess = ^uint32(0)
+
+var (
+	kernel32          = windows.NewLazySystemDLL("kernel32.dll")
+	procAttachConsole = kernel32.NewProc("AttachConsole")
+)
+
+// attachConsole connects stdout and stderr to the console of the parent process.
+// multiablo.exe is linked as a GUI application, so it does not get a console
+// of its own when started from a terminal. Redirected output is left untouched.
+func attachConsole() {
+	stdout, err := windows.GetStdHandle(windows.STD_OUTPUT_HANDLE)
+	if err == nil && stdout != 0 && stdout != windows.InvalidHandle {
+		return
+	}
+
+	r0, _, _ := procAttachConsole.Call(uintptr(attachParentProcess))
+	if r0 == 0 {
+		// Not started from a console
+		return
+	}
+
+	conout, err := os.OpenFile("CONOUT$", os.O_WRONLY, 0)
+	if err != nil {
+		return
+	}
+	os.Stdout = conout
+	os.Stderr = conout
+}
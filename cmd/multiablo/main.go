@@ -1,10 +1,9 @@
 package main
 
 import (
-	"github.com/chenwei791129/multiablo/internal/gui"
+	"os"
 )
 
 func main() {
-	app := gui.NewApp()
-	app.Run()
+	os.Exit(run(os.Args[1:]))
 }
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/chenwei791129/multiablo/internal/engine"
+)
+
+// jsonEvent is the JSON representation of an engine event
+type jsonEvent struct {
+	Time      time.Time `json:"time"`
+	Type      string    `json:"type"`
+	Process   string    `json:"process,omitempty"`
+	PID       uint32    `json:"pid,omitempty"`
+	Count     int       `json:"count,omitempty"`
+	Path      string    `json:"path,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	BlockedBy []uint32  `json:"blocked_by,omitempty"`
+	DryRun    bool      `json:"dry_run,omitempty"`
+	Message   string    `json:"message"`
+}
+
+// eventWriter prints engine events as log lines or JSON lines
+type eventWriter struct {
+	w       io.Writer
+	jsonOut bool
+	enc     *json.Encoder
+}
+
+// newEventWriter creates an event writer
+func newEventWriter(w io.Writer, jsonOut bool) *eventWriter {
+	return &eventWriter{
+		w:       w,
+		jsonOut: jsonOut,
+		enc:     json.NewEncoder(w),
+	}
+}
+
+// write prints a single event
+func (ew *eventWriter) write(event engine.Event) {
+	if !ew.jsonOut {
+		fmt.Fprintf(ew.w, "[%s] %s\n", event.Time.Format("15:04:05"), event.Message)
+		return
+	}
+
+	je := jsonEvent{
+		Time:      event.Time,
+		Type:      event.Type.String(),
+		Process:   event.ProcessName,
+		PID:       event.PID,
+		Count:     event.Count,
+		Path:      event.Path,
+		BlockedBy: event.BlockedBy,
+		DryRun:    event.DryRun,
+		Message:   event.Message,
+	}
+	if event.Err != nil {
+		je.Error = event.Err.Error()
+	}
+	_ = ew.enc.Encode(je)
+}
+
+// drain prints every event left in a subscription channel.
+// The channel must already be closed by its cancel function.
+func (ew *eventWriter) drain(events <-chan engine.Event) {
+	for event := range events {
+		ew.write(event)
+	}
+}
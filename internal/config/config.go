@@ -0,0 +1,276 @@
+// Package config loads, validates and saves the Multiablo settings file.
+//
+// The settings file is a versioned JSON document stored under the user
+// configuration directory. Missing settings take their default values,
+// which match the behaviour of Multiablo without a settings file.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/chenwei791129/multiablo/internal/game"
+	"github.com/chenwei791129/multiablo/internal/handle"
+	"github.com/chenwei791129/multiablo/pkg/d2r"
+)
+
+const (
+	// CurrentVersion is the settings schema version written by this build
+	CurrentVersion = 3
+
+	// dirName is the directory created under the user configuration directory
+	dirName = "multiablo"
+
+	// fileName is the name of the settings file
+	fileName = "config.json"
+
+	// gamesDirName is the directory next to the settings file holding user game definitions
+	gamesDirName = "games"
+)
+
+// Agent.exe strategies selectable with agent_strategy
+const (
+	// StrategyKillRelaunch terminates Agent.exe after agent_kill_threshold and relaunches it
+	StrategyKillRelaunch = "kill_relaunch"
+	// StrategyKillOnly terminates Agent.exe after agent_kill_threshold without relaunching it
+	StrategyKillOnly = "kill_only"
+	// StrategyLeaveAlone never terminates Agent.exe
+	StrategyLeaveAlone = "leave_alone"
+	// StrategyDuringLaunch behaves like kill_relaunch while Multiablo launches D2R, and leaves Agent.exe alone otherwise
+	StrategyDuringLaunch = "during_launch"
+	// StrategyMinInstances behaves like kill_relaunch while at least agent_min_instances D2R instances run
+	StrategyMinInstances = "min_instances"
+)
+
+// AgentStrategies lists the valid agent_strategy values
+var AgentStrategies = []string{
+	StrategyKillRelaunch, StrategyKillOnly, StrategyLeaveAlone, StrategyDuringLaunch, StrategyMinInstances,
+}
+
+// Config holds the user-tunable settings
+type Config struct {
+	// Version is the schema version of the settings file
+	Version int `json:"version"`
+
+	// ProcessPollInterval is how often the process list is checked for new
+	// processes when process start events are unavailable
+	ProcessPollInterval Duration `json:"process_poll_interval"`
+
+	// HandleCheckInterval is how often D2R processes whose single-instance
+	// handle is not closed yet are checked again
+	HandleCheckInterval Duration `json:"handle_check_interval"`
+
+	// AgentCheckInterval is how often Agent.exe processes are checked
+	AgentCheckInterval Duration `json:"agent_check_interval"`
+
+	// UIUpdateInterval throttles how often the GUI status cards are refreshed
+	UIUpdateInterval Duration `json:"ui_update_interval"`
+
+	// AgentStrategy selects when Agent.exe is terminated and whether it is relaunched
+	AgentStrategy string `json:"agent_strategy"`
+
+	// AgentMinInstances is the number of running D2R instances from which
+	// the min_instances strategy terminates Agent.exe
+	AgentMinInstances int `json:"agent_min_instances"`
+
+	// AgentKillThreshold is the Agent.exe uptime after which it is terminated
+	AgentKillThreshold Duration `json:"agent_kill_threshold"`
+
+	// AgentCrashWindow is how long a relaunched Agent.exe has to keep running
+	// to count as started; exiting earlier counts as a crash
+	AgentCrashWindow Duration `json:"agent_crash_window"`
+
+	// AgentPath is used to relaunch Agent.exe when the path of the running process is unknown
+	AgentPath string `json:"agent_path"`
+
+	// HandleMatchRules select the handles D2R uses to prevent multiple instances;
+	// a handle matching any rule is closed. They replace the rules of the
+	// built-in D2R game definition.
+	HandleMatchRules []handle.MatchRule `json:"handle_match_rules"`
+
+	// EnabledGames are the IDs of the game definitions to monitor
+	EnabledGames []string `json:"enabled_games"`
+
+	// MaxLogLines caps the number of lines kept in the GUI activity log
+	MaxLogLines int `json:"max_log_lines"`
+
+	// Language is the UI language code such as "zh_TW"; empty follows the system language
+	Language string `json:"language"`
+
+	// LaunchProfiles are the D2R launch profiles offered by the GUI
+	LaunchProfiles []LaunchProfile `json:"launch_profiles"`
+
+	// LaunchTimeout is how long a launch waits for the single-instance handle
+	// of the new D2R instance to be closed
+	LaunchTimeout Duration `json:"launch_timeout"`
+
+	// LaunchRetries is how many more times a failed launch is attempted
+	LaunchRetries int `json:"launch_retries"`
+}
+
+// Default returns the settings used when no settings file exists
+func Default() Config {
+	return Config{
+		Version:             CurrentVersion,
+		ProcessPollInterval: Duration(250 * time.Millisecond),
+		HandleCheckInterval: Duration(1 * time.Second),
+		AgentCheckInterval:  Duration(1 * time.Second),
+		UIUpdateInterval:    Duration(500 * time.Millisecond),
+		AgentStrategy:       StrategyKillRelaunch,
+		AgentMinInstances:   2,
+		AgentKillThreshold:  Duration(7 * time.Second),
+		AgentCrashWindow:    Duration(5 * time.Second),
+		AgentPath:           d2r.DefaultAgentPath,
+		HandleMatchRules:    DefaultMatchRules(),
+		EnabledGames:        []string{game.D2RID},
+		MaxLogLines:         500,
+		LaunchProfiles:      []LaunchProfile{},
+		LaunchTimeout:       Duration(1 * time.Minute),
+		LaunchRetries:       2,
+	}
+}
+
+// DefaultMatchRules returns the rules matching the single-instance event of D2R
+func DefaultMatchRules() []handle.MatchRule {
+	return game.D2R().HandleMatchRules
+}
+
+// DefaultPath returns the location of the settings file,
+// e.g. %AppData%\multiablo\config.json on Windows
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate user config directory: %w", err)
+	}
+	return filepath.Join(dir, dirName, fileName), nil
+}
+
+// GamesDir returns the directory holding user game definitions next to the
+// settings file at path, e.g. %AppData%\multiablo\games on Windows
+func GamesDir(path string) string {
+	return filepath.Join(filepath.Dir(path), gamesDirName)
+}
+
+// Game returns the definition with the given ID from registry, with the
+// built-in D2R definition customized by these settings
+func (c Config) Game(registry *game.Registry, id string) (game.Definition, bool) {
+	d, ok := registry.Get(id)
+	if !ok || id != game.D2RID {
+		return d, ok
+	}
+
+	d.HandleMatchRules = c.HandleMatchRules
+	helpers := make([]game.Helper, 0, len(d.Helpers))
+	for _, h := range d.Helpers {
+		if strings.EqualFold(h.ProcessName, d2r.AgentProcessName) {
+			h.DefaultPath = c.AgentPath
+		}
+		helpers = append(helpers, h)
+	}
+	d.Helpers = helpers
+	return d, true
+}
+
+// Games returns the enabled definitions from registry, customized by these
+// settings, and the enabled IDs that registry does not define
+func (c Config) Games(registry *game.Registry) ([]game.Definition, []string) {
+	enabled, unknown := registry.Enabled(c.EnabledGames)
+	for i, d := range enabled {
+		enabled[i], _ = c.Game(registry, d.ID)
+	}
+	return enabled, unknown
+}
+
+// Load reads and validates the settings file at path.
+// A missing file is not an error and yields the default settings. On any
+// other error the default settings are returned together with the error,
+// so callers can report it and keep running.
+func Load(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Default(), nil
+	}
+	if err != nil {
+		return Default(), fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	cfg, err := Parse(data)
+	if err != nil {
+		return Default(), fmt.Errorf("invalid settings file %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Parse decodes and validates a settings document, migrating it from older
+// schema versions first. Settings missing from the document take their
+// default values.
+func Parse(data []byte) (Config, error) {
+	raw, err := migrate(data)
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg := Default()
+	// Decoding into the default rules would fill the fields a rule leaves
+	// out from the default rule at the same index
+	cfg.HandleMatchRules = nil
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return Config{}, fmt.Errorf("failed to decode settings: %w", err)
+	}
+	if cfg.HandleMatchRules == nil {
+		cfg.HandleMatchRules = DefaultMatchRules()
+	}
+	cfg.Version = CurrentVersion
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// Save validates cfg and writes it to path, creating the directory if needed.
+// The file is replaced atomically so a crash never leaves a truncated file.
+func Save(path string, cfg Config) error {
+	cfg.Version = CurrentVersion
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode settings: %w", err)
+	}
+	data = append(data, '\n')
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), fileName+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/chenwei791129/multiablo/internal/game"
+	"github.com/chenwei791129/multiablo/internal/handle"
+	"github.com/chenwei791129/multiablo/pkg/d2r"
+)
+
+func TestConfigGames(t *testing.T) {
+	other := game.Definition{
+		ID:               "other",
+		ProcessNames:     []string{"Other.exe"},
+		HandleMatchRules: []handle.MatchRule{{Type: "Mutant", Match: handle.MatchExact, Pattern: "Other"}},
+		Helpers:          []game.Helper{{ProcessName: d2r.AgentProcessName, DefaultPath: `C:\Other\Agent.exe`}},
+	}
+	registry, err := game.NewRegistry(append(game.Builtin(), other))
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+
+	cfg := Default()
+	cfg.AgentPath = `D:\Battle.net\Agent.exe`
+	cfg.HandleMatchRules = []handle.MatchRule{{Type: "Event", Match: handle.MatchExact, Pattern: "Custom"}}
+	cfg.EnabledGames = []string{"other", "missing", game.D2RID}
+
+	enabled, unknown := cfg.Games(registry)
+	if len(enabled) != 2 || enabled[0].ID != game.D2RID || enabled[1].ID != "other" {
+		t.Fatalf("Games() = %+v, want d2r and other", enabled)
+	}
+	if want := []string{"missing"}; !slices.Equal(unknown, want) {
+		t.Errorf("Games() unknown = %v, want %v", unknown, want)
+	}
+
+	// The settings customize the built-in D2R definition only
+	if !slices.Equal(enabled[0].HandleMatchRules, cfg.HandleMatchRules) {
+		t.Errorf("D2R rules = %+v, want the configured %+v", enabled[0].HandleMatchRules, cfg.HandleMatchRules)
+	}
+	if h, _ := enabled[0].Helper(d2r.AgentProcessName); h.DefaultPath != cfg.AgentPath {
+		t.Errorf("D2R Agent.exe path = %q, want %q", h.DefaultPath, cfg.AgentPath)
+	}
+	if !slices.Equal(enabled[1].HandleMatchRules, other.HandleMatchRules) {
+		t.Errorf("other rules = %+v, want its own %+v", enabled[1].HandleMatchRules, other.HandleMatchRules)
+	}
+	if h, _ := enabled[1].Helper(d2r.AgentProcessName); h.DefaultPath != `C:\Other\Agent.exe` {
+		t.Errorf("other Agent.exe path = %q, want its own path", h.DefaultPath)
+	}
+	if h, _ := game.D2R().Helper(d2r.AgentProcessName); h.DefaultPath != d2r.DefaultAgentPath {
+		t.Errorf("the built-in definition was changed: Agent.exe path %q", h.DefaultPath)
+	}
+}
+
+func TestParseEnabledGames(t *testing.T) {
+	cfg, err := Parse([]byte(`{"version": 3, "enabled_games": ["d2r", "other"]}`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if want := []string{game.D2RID, "other"}; !slices.Equal(cfg.EnabledGames, want) {
+		t.Errorf("EnabledGames = %v, want %v", cfg.EnabledGames, want)
+	}
+
+	for _, doc := range []string{
+		`{"version": 3, "enabled_games": []}`,
+		`{"version": 3, "enabled_games": [""]}`,
+		`{"version": 3, "enabled_games": ["d2r", "d2r"]}`,
+	} {
+		if _, err := Parse([]byte(doc)); err == nil {
+			t.Errorf("Parse(%s) succeeded, want an error", doc)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/chenwei791129/multiablo/internal/handle"
+)
+
+// migration upgrades a decoded settings document by one schema version, in place
+type migration func(doc map[string]json.RawMessage) error
+
+// migrations holds the upgrade from version N to N+1 at index N-1.
+// When the schema changes, bump CurrentVersion and append the upgrade here
+// instead of changing how older files are read.
+var migrations = []migration{
+	migrateEventNameToRules,
+	migrateAgentKillerToStrategy,
+}
+
+// migrate upgrades a settings document to CurrentVersion.
+// Documents without a version field are treated as version 1.
+func migrate(data []byte) ([]byte, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("settings file is not a JSON object: %w", err)
+	}
+	if doc == nil {
+		return nil, errors.New("settings file is not a JSON object")
+	}
+
+	version := 1
+	if raw, ok := doc["version"]; ok {
+		if err := json.Unmarshal(raw, &version); err != nil {
+			return nil, fmt.Errorf("version: must be an integer: %s", raw)
+		}
+	}
+
+	switch {
+	case version < 1:
+		return nil, fmt.Errorf("version: must be at least 1 (got %d)", version)
+	case version > CurrentVersion:
+		return nil, fmt.Errorf("version: %d is newer than the supported version %d, please update Multiablo", version, CurrentVersion)
+	}
+
+	for ; version < CurrentVersion; version++ {
+		if err := migrations[version-1](doc); err != nil {
+			return nil, fmt.Errorf("failed to migrate settings from version %d: %w", version, err)
+		}
+	}
+
+	versionJSON, err := json.Marshal(CurrentVersion)
+	if err != nil {
+		return nil, err
+	}
+	doc["version"] = versionJSON
+
+	return json.Marshal(doc)
+}
+
+// migrateEventNameToRules replaces single_instance_event_name (version 1)
+// with an equivalent handle_match_rules entry (version 2)
+func migrateEventNameToRules(doc map[string]json.RawMessage) error {
+	raw, ok := doc["single_instance_event_name"]
+	if !ok {
+		return nil
+	}
+	delete(doc, "single_instance_event_name")
+
+	var name string
+	if err := json.Unmarshal(raw, &name); err != nil {
+		return fmt.Errorf("single_instance_event_name: must be a string: %s", raw)
+	}
+	if _, ok := doc["handle_match_rules"]; ok || name == "" {
+		return nil
+	}
+
+	rules, err := json.Marshal([]handle.MatchRule{{
+		Type:    "Event",
+		Match:   handle.MatchContains,
+		Pattern: name,
+	}})
+	if err != nil {
+		return err
+	}
+	doc["handle_match_rules"] = rules
+	return nil
+}
+
+// migrateAgentKillerToStrategy replaces agent_killer_enabled (version 2)
+// with the equivalent agent_strategy (version 3)
+func migrateAgentKillerToStrategy(doc map[string]json.RawMessage) error {
+	raw, ok := doc["agent_killer_enabled"]
+	if !ok {
+		return nil
+	}
+	delete(doc, "agent_killer_enabled")
+
+	var enabled bool
+	if err := json.Unmarshal(raw, &enabled); err != nil {
+		return fmt.Errorf("agent_killer_enabled: must be a boolean: %s", raw)
+	}
+
+	strategy := StrategyKillRelaunch
+	if !enabled {
+		strategy = StrategyLeaveAlone
+	}
+	data, err := json.Marshal(strategy)
+	if err != nil {
+		return err
+	}
+	doc["agent_strategy"] = data
+	return nil
+}
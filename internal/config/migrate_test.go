@@ -0,0 +1,67 @@
+package config
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/chenwei791129/multiablo/internal/handle"
+)
+
+func TestParseMigratesEventName(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want []handle.MatchRule
+	}{
+		{
+			name: "event name becomes a contains rule",
+			doc:  `{"version": 1, "single_instance_event_name": "Check For Other Instances"}`,
+			want: []handle.MatchRule{{Type: "Event", Match: handle.MatchContains, Pattern: "Check For Other Instances"}},
+		},
+		{
+			name: "missing version is version 1",
+			doc:  `{"single_instance_event_name": "Other Name"}`,
+			want: []handle.MatchRule{{Type: "Event", Match: handle.MatchContains, Pattern: "Other Name"}},
+		},
+		{
+			name: "empty event name keeps the default rules",
+			doc:  `{"version": 1, "single_instance_event_name": ""}`,
+			want: DefaultMatchRules(),
+		},
+		{
+			name: "existing rules win over the event name",
+			doc: `{"version": 1, "single_instance_event_name": "Ignored",
+				"handle_match_rules": [{"type": "Mutant", "match": "exact", "pattern": "Kept"}]}`,
+			want: []handle.MatchRule{{Type: "Mutant", Match: handle.MatchExact, Pattern: "Kept"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Parse([]byte(tt.doc))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if cfg.Version != CurrentVersion {
+				t.Errorf("Version = %d, want %d", cfg.Version, CurrentVersion)
+			}
+			if !slices.Equal(cfg.HandleMatchRules, tt.want) {
+				t.Errorf("HandleMatchRules = %+v, want %+v", cfg.HandleMatchRules, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRejectsInvalidRules(t *testing.T) {
+	docs := []string{
+		`{"version": 1, "single_instance_event_name": 42}`,
+		`{"version": 2, "handle_match_rules": []}`,
+		`{"version": 2, "handle_match_rules": [{"type": "Event", "match": "regex", "pattern": "("}]}`,
+		`{"version": 99}`,
+	}
+	for _, doc := range docs {
+		if _, err := Parse([]byte(doc)); err == nil {
+			t.Errorf("Parse(%s) succeeded, want an error", doc)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// LaunchProfile describes how to start one D2R instance, typically one per account
+type LaunchProfile struct {
+	// Name identifies the profile and must be unique
+	Name string `json:"name"`
+
+	// DisplayName is shown in the GUI; empty shows Name
+	DisplayName string `json:"display_name"`
+
+	// Path is the full path of D2R.exe
+	Path string `json:"path"`
+
+	// Args are the command-line arguments, e.g. -mod, -txt, -direct or -ns
+	Args []string `json:"args"`
+
+	// WorkDir is the working directory; empty uses the directory of D2R.exe
+	WorkDir string `json:"work_dir"`
+
+	// Env holds extra "KEY=value" variables added to the current environment
+	Env []string `json:"env"`
+}
+
+// Label returns the name to show for the profile
+func (p LaunchProfile) Label() string {
+	if strings.TrimSpace(p.DisplayName) != "" {
+		return p.DisplayName
+	}
+	return p.Name
+}
+
+// Dir returns the working directory to start the profile in
+func (p LaunchProfile) Dir() string {
+	if strings.TrimSpace(p.WorkDir) != "" {
+		return p.WorkDir
+	}
+	return filepath.Dir(p.Path)
+}
+
+// Profile returns the launch profile with the given name
+func (c Config) Profile(name string) (LaunchProfile, bool) {
+	for _, p := range c.LaunchProfiles {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return LaunchProfile{}, false
+}
+
+// validateProfiles checks that every launch profile is complete and uniquely named
+func validateProfiles(profiles []LaunchProfile) error {
+	var errs []error
+	seen := make(map[string]bool, len(profiles))
+
+	for i, p := range profiles {
+		field := fmt.Sprintf("launch_profiles[%d]", i)
+		switch {
+		case strings.TrimSpace(p.Name) == "":
+			errs = append(errs, fmt.Errorf("%s.name: must not be empty", field))
+		case seen[p.Name]:
+			errs = append(errs, fmt.Errorf("%s.name: duplicate profile name %q", field, p.Name))
+		}
+		seen[p.Name] = true
+
+		if strings.TrimSpace(p.Path) == "" {
+			errs = append(errs, fmt.Errorf("%s.path: must not be empty", field))
+		}
+
+		for j, variable := range p.Env {
+			if key, _, ok := strings.Cut(variable, "="); !ok || strings.TrimSpace(key) == "" {
+				errs = append(errs, fmt.Errorf("%s.env[%d]: %q is not in KEY=value form", field, j, variable))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
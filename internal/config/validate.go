@@ -0,0 +1,111 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/chenwei791129/multiablo/internal/handle"
+	"github.com/chenwei791129/multiablo/internal/i18n"
+)
+
+// Limits for the numeric settings
+const (
+	minPollInterval = 50 * time.Millisecond
+	maxPollInterval = 10 * time.Second
+
+	minCheckInterval = 100 * time.Millisecond
+	maxCheckInterval = 1 * time.Minute
+
+	minUIUpdateInterval = 50 * time.Millisecond
+	maxUIUpdateInterval = 10 * time.Second
+
+	minAgentKillThreshold = 1 * time.Second
+	maxAgentKillThreshold = 10 * time.Minute
+
+	minAgentCrashWindow = 1 * time.Second
+	maxAgentCrashWindow = 1 * time.Minute
+
+	minAgentInstances = 1
+	maxAgentInstances = 64
+
+	minLogLines = 10
+	maxLogLines = 100000
+
+	minLaunchTimeout = 5 * time.Second
+	maxLaunchTimeout = 10 * time.Minute
+
+	maxLaunchRetries = 10
+)
+
+// Validate checks every setting and reports all problems at once
+func (c Config) Validate() error {
+	var errs []error
+
+	errs = append(errs,
+		checkDuration("process_poll_interval", c.ProcessPollInterval, minPollInterval, maxPollInterval),
+		checkDuration("handle_check_interval", c.HandleCheckInterval, minCheckInterval, maxCheckInterval),
+		checkDuration("agent_check_interval", c.AgentCheckInterval, minCheckInterval, maxCheckInterval),
+		checkDuration("ui_update_interval", c.UIUpdateInterval, minUIUpdateInterval, maxUIUpdateInterval),
+		checkDuration("agent_kill_threshold", c.AgentKillThreshold, minAgentKillThreshold, maxAgentKillThreshold),
+		checkDuration("agent_crash_window", c.AgentCrashWindow, minAgentCrashWindow, maxAgentCrashWindow),
+		checkDuration("launch_timeout", c.LaunchTimeout, minLaunchTimeout, maxLaunchTimeout),
+		validateProfiles(c.LaunchProfiles),
+	)
+
+	if !slices.Contains(AgentStrategies, c.AgentStrategy) {
+		errs = append(errs, fmt.Errorf("agent_strategy: must be one of %s (got %q)", strings.Join(AgentStrategies, ", "), c.AgentStrategy))
+	}
+	if c.AgentMinInstances < minAgentInstances || c.AgentMinInstances > maxAgentInstances {
+		errs = append(errs, fmt.Errorf("agent_min_instances: must be between %d and %d (got %d)", minAgentInstances, maxAgentInstances, c.AgentMinInstances))
+	}
+	if strings.TrimSpace(c.AgentPath) == "" {
+		errs = append(errs, errors.New("agent_path: must not be empty"))
+	}
+	if _, err := handle.NewMatcher(c.HandleMatchRules); err != nil {
+		errs = append(errs, fmt.Errorf("handle_match_rules: %w", err))
+	}
+	errs = append(errs, validateEnabledGames(c.EnabledGames))
+	if c.MaxLogLines < minLogLines || c.MaxLogLines > maxLogLines {
+		errs = append(errs, fmt.Errorf("max_log_lines: must be between %d and %d (got %d)", minLogLines, maxLogLines, c.MaxLogLines))
+	}
+	if c.LaunchRetries < 0 || c.LaunchRetries > maxLaunchRetries {
+		errs = append(errs, fmt.Errorf("launch_retries: must be between 0 and %d (got %d)", maxLaunchRetries, c.LaunchRetries))
+	}
+
+	if c.Language != "" && !slices.Contains(i18n.GetAvailableLanguages(), c.Language) {
+		errs = append(errs, fmt.Errorf("language: must be empty or one of %s (got %q)",
+			strings.Join(i18n.GetAvailableLanguages(), ", "), c.Language))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateEnabledGames checks that at least one game is enabled and that no ID is repeated.
+// Whether the IDs are defined is only known once the game definitions are loaded.
+func validateEnabledGames(ids []string) error {
+	if len(ids) == 0 {
+		return errors.New("enabled_games: at least one game must be enabled")
+	}
+
+	var errs []error
+	for i, id := range ids {
+		switch {
+		case strings.TrimSpace(id) == "":
+			errs = append(errs, fmt.Errorf("enabled_games[%d]: must not be empty", i))
+		case slices.Index(ids, id) < i:
+			errs = append(errs, fmt.Errorf("enabled_games[%d]: duplicate game %q", i, id))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkDuration reports a duration setting outside [lower, upper]
+func checkDuration(name string, d Duration, lower, upper time.Duration) error {
+	if d.Std() < lower || d.Std() > upper {
+		return fmt.Errorf("%s: must be between %v and %v (got %v)", name, lower, upper, d)
+	}
+	return nil
+}
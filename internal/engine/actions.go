@@ -0,0 +1,432 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/chenwei791129/multiablo/internal/game"
+	"github.com/chenwei791129/multiablo/internal/handle"
+	"github.com/chenwei791129/multiablo/internal/i18n"
+	"github.com/chenwei791129/multiablo/internal/process"
+)
+
+// InstanceReport describes a game process and the single-instance handles it holds
+type InstanceReport struct {
+	PID         uint32
+	ProcessName string
+
+	// Game is the ID of the game definition the process belongs to
+	Game string
+
+	Handles []handle.HandleInfo
+	Err     error
+}
+
+// gameProcess is a running process of an enabled game
+type gameProcess struct {
+	process.ProcessInfo
+	game gameEntry
+}
+
+// gameProcesses lists the running processes of the enabled games
+func (e *Engine) gameProcesses() ([]gameProcess, error) {
+	games := e.enabledGames()
+	if len(games.games) == 0 {
+		return nil, ErrNoGames
+	}
+
+	names := games.processNames()
+	processes, err := e.processes.FindProcessesByNames(names)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find %s: %w", strings.Join(names, ", "), err)
+	}
+
+	var found []gameProcess
+	for _, proc := range processes {
+		if g, ok := games.gameOf(proc.Name); ok {
+			found = append(found, gameProcess{ProcessInfo: proc, game: g})
+		}
+	}
+	return found, nil
+}
+
+// findGameProcess returns the running game process with the given PID
+func (e *Engine) findGameProcess(pid uint32) (gameProcess, error) {
+	processes, err := e.gameProcesses()
+	if err != nil {
+		return gameProcess{}, err
+	}
+	for _, proc := range processes {
+		if proc.PID == pid {
+			return proc, nil
+		}
+	}
+	return gameProcess{}, fmt.Errorf("%w: PID %d is not a process of an enabled game", process.ErrNoProcess, pid)
+}
+
+// Scan lists the running processes of the enabled games and their
+// single-instance handles without closing anything. Failing to inspect one
+// process is reported in its InstanceReport rather than failing the whole scan.
+func (e *Engine) Scan() ([]InstanceReport, error) {
+	processes, err := e.gameProcesses()
+	if err != nil {
+		return nil, err
+	}
+
+	pids := make([]uint32, 0, len(processes))
+	for _, proc := range processes {
+		pids = append(pids, proc.PID)
+	}
+	snapshot, snapshotErr := e.instanceSnapshot(pids)
+
+	reports := make([]InstanceReport, 0, len(processes))
+	for _, proc := range processes {
+		var handles []handle.HandleInfo
+		err := snapshotErr
+		if err == nil {
+			handles, err = snapshot.Find(proc.PID, proc.game.matcher)
+		}
+		reports = append(reports, InstanceReport{
+			PID:         proc.PID,
+			ProcessName: proc.Name,
+			Game:        proc.game.ID,
+			Handles:     handles,
+			Err:         err,
+		})
+	}
+
+	return reports, nil
+}
+
+// InspectHandles lists the handles of any process that pass the filter,
+// with the names of its named objects. Names of other object types, such as
+// File, are not queried because the query can hang.
+func (e *Engine) InspectHandles(pid uint32, filter handle.Filter) ([]handle.HandleInfo, error) {
+	nameTypes := handle.NamedObjectTypes
+	if filter.TypeName != "" {
+		// Only resolve the names that can pass the filter
+		nameTypes = nil
+		if slices.Contains(handle.NamedObjectTypes, filter.TypeName) {
+			nameTypes = []string{filter.TypeName}
+		}
+	}
+
+	snapshot, err := e.snapshot([]uint32{pid}, nameTypes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list handles: %w", err)
+	}
+	handles, err := snapshot.Handles(pid)
+	if err != nil {
+		return nil, err
+	}
+	return handle.FilterHandles(handles, filter), nil
+}
+
+// snapshot takes a handle snapshot of the given processes, resolving the
+// names of nameTypes, and reports the name queries that timed out or were refused
+func (e *Engine) snapshot(pids []uint32, nameTypes []string) (*handle.Snapshot, error) {
+	snapshot, err := e.handles.Snapshot(pids, nameTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	if timeouts := snapshot.Timeouts(); timeouts > 0 {
+		e.mu.Lock()
+		e.totalNameTimeouts += timeouts
+		e.mu.Unlock()
+
+		e.emit(Event{
+			Type:    EventError,
+			Count:   timeouts,
+			Err:     handle.ErrNameTimeout,
+			Message: fmt.Sprintf(i18n.Get("%d handle name query(ies) timed out and were skipped"), timeouts),
+		})
+	}
+	if refused := snapshot.Refused(); refused > 0 {
+		e.mu.Lock()
+		e.totalNameRefusals += refused
+		e.mu.Unlock()
+
+		e.emit(Event{
+			Type:    EventError,
+			Count:   refused,
+			Err:     handle.ErrResolverBusy,
+			Message: fmt.Sprintf(i18n.Get("%d handle name query(ies) were refused because earlier queries are stuck"), refused),
+		})
+	}
+
+	return snapshot, nil
+}
+
+// instanceSnapshot takes a handle snapshot of game processes, resolving the
+// names of the object types the match rules of the enabled games need
+func (e *Engine) instanceSnapshot(pids []uint32) (*handle.Snapshot, error) {
+	matcher, err := e.handleMatcher()
+	if err != nil {
+		return nil, err
+	}
+	return e.snapshot(pids, matcher.Types())
+}
+
+// CloseHandles closes the single-instance handles of a process of an enabled
+// game and verifies that they are gone, reporting objects that other
+// processes keep alive. In observe-only mode it reports the handles and
+// returns their count with ErrDryRun.
+func (e *Engine) CloseHandles(pid uint32) (int, error) {
+	proc, err := e.findGameProcess(pid)
+	if err != nil {
+		return 0, err
+	}
+
+	before, err := e.instanceSnapshot([]uint32{pid})
+	if err != nil {
+		return 0, fmt.Errorf("failed to find handles: %w", err)
+	}
+	closedCount, err := e.closeSnapshotHandles(before, pid, proc.Name, proc.game.matcher)
+	if err != nil {
+		return closedCount, err
+	}
+
+	after, err := e.instanceSnapshot([]uint32{pid})
+	if err != nil {
+		return closedCount, fmt.Errorf("failed to verify: %w", err)
+	}
+	return closedCount, e.verifyClosed(before, after, pid, proc.Name, proc.game.matcher)
+}
+
+// closeSnapshotHandles closes the handles that a snapshot lists for a game
+// process and that matcher selects
+func (e *Engine) closeSnapshotHandles(snapshot *handle.Snapshot, pid uint32, processName string, matcher *handle.Matcher) (int, error) {
+	if e.DryRun() {
+		return e.reportSnapshotHandles(snapshot, pid, processName, matcher)
+	}
+
+	closedCount, err := handle.CloseSnapshotHandles(e.handles, snapshot, pid, matcher)
+	if err != nil {
+		return 0, err
+	}
+
+	e.mu.Lock()
+	e.totalHandlesClosed += closedCount
+	e.mu.Unlock()
+
+	e.emit(Event{
+		Type:        EventHandleClosed,
+		ProcessName: processName,
+		PID:         pid,
+		Count:       closedCount,
+		Message:     fmt.Sprintf(i18n.Get("Closed %d handle(s) for %s (PID: %d)"), closedCount, processName, pid),
+	})
+
+	return closedCount, nil
+}
+
+// reportSnapshotHandles reports the handles closeSnapshotHandles would close
+// and returns their count with ErrDryRun
+func (e *Engine) reportSnapshotHandles(snapshot *handle.Snapshot, pid uint32, processName string, matcher *handle.Matcher) (int, error) {
+	handles, err := snapshot.Find(pid, matcher)
+	if err != nil {
+		return 0, err
+	}
+	if len(handles) == 0 {
+		return 0, fmt.Errorf("%w: %s", handle.ErrNoHandles, matcher)
+	}
+
+	details := make([]string, 0, len(handles))
+	for _, h := range handles {
+		details = append(details, fmt.Sprintf("0x%X %s %s", h.Handle, h.TypeName, h.Name))
+	}
+	e.emit(Event{
+		Type:        EventHandleClosed,
+		ProcessName: processName,
+		PID:         pid,
+		Count:       len(handles),
+		DryRun:      true,
+		Message: fmt.Sprintf(i18n.Get("[dry run] Would close %d handle(s) for %s (PID: %d): %s"),
+			len(handles), processName, pid, strings.Join(details, "; ")),
+	})
+
+	return len(handles), ErrDryRun
+}
+
+// AgentPath returns the executable path of a running launcher helper such
+// as Agent.exe, falling back to the default path of the first helper
+func (e *Engine) AgentPath() string {
+	helpers := e.enabledGames().helpers()
+	for _, h := range helpers {
+		processes, err := e.processes.FindProcessesByName(h.ProcessName)
+		if err == nil && len(processes) > 0 {
+			return e.helperPath(h, processes[0].PID)
+		}
+	}
+	if len(helpers) > 0 {
+		return helpers[0].DefaultPath
+	}
+	return e.settings().AgentPath
+}
+
+// helperPath returns the executable path of a launcher helper process,
+// falling back to the default path of the helper
+func (e *Engine) helperPath(h game.Helper, pid uint32) string {
+	path, err := e.processes.GetProcessExecutablePath(pid)
+	if err != nil || path == "" {
+		return h.DefaultPath
+	}
+	return path
+}
+
+// helperStatus collects what is needed to terminate a launcher helper
+// process and to relaunch it the way it was started: its creation time,
+// executable path and command-line arguments. Details missing from proc,
+// which process lists leave empty, are queried.
+func (e *Engine) helperStatus(h game.Helper, proc process.ProcessInfo) ProcessStatus {
+	status := ProcessStatus{
+		PID:       proc.PID,
+		Name:      proc.Name,
+		ParentPID: proc.ParentPID,
+		StartTime: proc.CreationTime,
+		Path:      proc.Path,
+		Args:      proc.Args(),
+	}
+	if status.StartTime.IsZero() {
+		status.StartTime, _ = e.processes.GetProcessCreationTime(proc.PID)
+	}
+	if status.Path == "" {
+		status.Path = e.helperPath(h, proc.PID)
+	}
+	if proc.CommandLine == nil {
+		if args, err := e.processes.GetProcessCommandLine(proc.PID); err == nil && len(args) > 1 {
+			status.Args = args[1:]
+		}
+	}
+	return status
+}
+
+// KillAgents terminates the launcher helper processes, such as Agent.exe,
+// of all enabled games one by one. It returns the number of terminated
+// processes and the terminated helpers with the paths and arguments they
+// should be relaunched with. In observe-only mode it reports the processes
+// and returns their count and statuses with ErrDryRun.
+func (e *Engine) KillAgents() (int, []ProcessStatus, error) {
+	var (
+		killed []ProcessStatus
+		errs   []error
+		names  []string
+	)
+	dryRun := e.DryRun()
+	for _, h := range e.enabledGames().helpers() {
+		names = append(names, h.ProcessName)
+
+		processes, err := e.processes.FindProcessesByName(h.ProcessName)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to find %s: %w", h.ProcessName, err))
+			continue
+		}
+		for _, proc := range processes {
+			status := e.helperStatus(h, proc)
+			if err := e.killAgent(status); err != nil && !errors.Is(err, ErrDryRun) {
+				errs = append(errs, err)
+				continue
+			}
+			killed = append(killed, status)
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return len(killed), killed, err
+	}
+	if len(killed) == 0 {
+		return 0, nil, fmt.Errorf("%w: %s", process.ErrNoProcess, strings.Join(names, ", "))
+	}
+	if dryRun {
+		return len(killed), killed, ErrDryRun
+	}
+	return len(killed), killed, nil
+}
+
+// killAgent terminates a single launcher helper process. The creation time
+// of the process is checked first, so that a process that reused the PID
+// is never terminated. In observe-only mode it only reports the process
+// and returns ErrDryRun.
+func (e *Engine) killAgent(p ProcessStatus) error {
+	if e.DryRun() {
+		e.emit(Event{
+			Type:        EventAgentKilled,
+			ProcessName: p.Name,
+			PID:         p.PID,
+			Count:       1,
+			DryRun:      true,
+			Message:     fmt.Sprintf(i18n.Get("[dry run] Would terminate %s (PID: %d)"), p.Name, p.PID),
+		})
+		return ErrDryRun
+	}
+
+	if err := e.processes.KillProcess(p.PID, p.StartTime); err != nil {
+		e.emit(Event{
+			Type:        EventError,
+			ProcessName: p.Name,
+			PID:         p.PID,
+			Err:         err,
+			Message:     fmt.Sprintf(i18n.Get("Failed to terminate %s (PID: %d): %v"), p.Name, p.PID, err),
+		})
+		return err
+	}
+
+	e.mu.Lock()
+	e.totalAgentsKilled++
+	e.mu.Unlock()
+
+	e.emit(Event{
+		Type:        EventAgentKilled,
+		ProcessName: p.Name,
+		PID:         p.PID,
+		Count:       1,
+		Message:     fmt.Sprintf(i18n.Get("Terminated %s (PID: %d)"), p.Name, p.PID),
+	})
+	return nil
+}
+
+// RelaunchAgent starts a launcher helper such as Agent.exe from the given
+// path with the given arguments and returns its PID. In observe-only mode
+// it only reports the launch and returns ErrDryRun.
+func (e *Engine) RelaunchAgent(agentPath string, args ...string) (uint32, error) {
+	name := exeName(agentPath)
+	if e.DryRun() {
+		e.emit(Event{
+			Type:        EventAgentRelaunched,
+			ProcessName: name,
+			Path:        agentPath,
+			DryRun:      true,
+			Message:     fmt.Sprintf(i18n.Get("[dry run] Would relaunch %s from %s"), name, agentPath),
+		})
+		return 0, ErrDryRun
+	}
+
+	pid, err := e.processes.LaunchProcess(agentPath, process.LaunchOptions{Args: args})
+	if err != nil {
+		e.emit(Event{
+			Type:        EventError,
+			ProcessName: name,
+			Path:        agentPath,
+			Err:         err,
+			Message:     fmt.Sprintf(i18n.Get("Failed to relaunch %s: %v"), name, err),
+		})
+		return 0, err
+	}
+
+	e.emit(Event{
+		Type:        EventAgentRelaunched,
+		ProcessName: name,
+		PID:         pid,
+		Path:        agentPath,
+		Message:     fmt.Sprintf(i18n.Get("Relaunched %s successfully (PID: %d)"), name, pid),
+	})
+	return pid, nil
+}
+
+// exeName returns the file name of a Windows or slash-separated executable path
+func exeName(path string) string {
+	return path[strings.LastIndexAny(path, `\/`)+1:]
+}
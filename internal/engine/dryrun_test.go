@@ -0,0 +1,145 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/chenwei791129/multiablo/internal/config"
+	"github.com/chenwei791129/multiablo/internal/game"
+	"github.com/chenwei791129/multiablo/internal/handle"
+	"github.com/chenwei791129/multiablo/internal/process"
+	"github.com/chenwei791129/multiablo/pkg/d2r"
+)
+
+// testSingleInstance is the single-instance handle of a simulated D2R instance
+var testSingleInstance = handle.FakeHandle{
+	Value:    0x4,
+	TypeName: "Event",
+	Name:     `\Sessions\1\BaseNamedObjects\DiabloII Check For Other Instances`,
+}
+
+// instanceState returns the state of the tracked instance with the given PID
+func instanceState(t *testing.T, e *Engine, pid uint32) InstanceState {
+	t.Helper()
+	for _, inst := range e.Status().GameProcesses {
+		if inst.PID == pid {
+			return inst.State
+		}
+	}
+	t.Fatalf("PID %d is not tracked", pid)
+	return 0
+}
+
+func TestDryRunObservesInstances(t *testing.T) {
+	pf := process.NewFake(time.Now())
+	hf := handle.NewFake()
+	e := New(Options{Processes: pf, Handles: hf, DryRun: true})
+	events, unsubscribe := e.Subscribe()
+	defer unsubscribe()
+
+	pid := pf.AddProcess(process.FakeProcess{Name: d2r.ProcessName, StartTime: pf.Now()})
+	hf.SetHandles(pid, testSingleInstance)
+	e.trackInstance(process.ProcessInfo{PID: pid, Name: d2r.ProcessName, CreationTime: pf.Now()}, game.D2RID)
+
+	e.checkInstances()
+	if got := len(hf.Closed()); got != 0 {
+		t.Fatalf("closed %d handles in observe-only mode", got)
+	}
+	if state := instanceState(t, e, pid); state != InstanceObserved {
+		t.Errorf("state = %s, want %s", state, InstanceObserved)
+	}
+	var reported []Event
+	for _, event := range drainEvents(events) {
+		if event.Type == EventHandleClosed {
+			reported = append(reported, event)
+		}
+	}
+	if len(reported) != 1 || !reported[0].DryRun || reported[0].Count != 1 || reported[0].PID != pid {
+		t.Errorf("handle events = %+v, want one dry run event for 1 handle of PID %d", reported, pid)
+	}
+
+	// Observed instances are not scanned again while observing
+	e.checkInstances()
+	if got := hf.Snapshots(); got != 1 {
+		t.Errorf("took %d snapshots, want 1", got)
+	}
+
+	// Turning observe-only mode off scans the observed instance for real
+	e.SetDryRun(false)
+	e.checkInstances()
+	if got := len(hf.Closed()); got != 1 {
+		t.Fatalf("closed %d handles after observe-only mode was turned off, want 1", got)
+	}
+	if state := instanceState(t, e, pid); state != InstanceVerified {
+		t.Errorf("state = %s, want %s", state, InstanceVerified)
+	}
+	for _, event := range drainEvents(events) {
+		if event.DryRun {
+			t.Errorf("dry run event %+v after observe-only mode was turned off", event)
+		}
+	}
+}
+
+func TestCloseHandlesDryRun(t *testing.T) {
+	pf := process.NewFake(time.Now())
+	hf := handle.NewFake()
+	e := New(Options{Processes: pf, Handles: hf, DryRun: true})
+
+	pid := pf.AddProcess(process.FakeProcess{Name: d2r.ProcessName, StartTime: pf.Now()})
+	hf.SetHandles(pid, testSingleInstance, handle.FakeHandle{Value: 0x8, TypeName: "Event", Name: "Other"})
+
+	count, err := e.CloseHandles(pid)
+	if count != 1 || !errors.Is(err, ErrDryRun) {
+		t.Errorf("CloseHandles() = %d, %v, want 1, %v", count, err, ErrDryRun)
+	}
+	if got := len(hf.Closed()); got != 0 {
+		t.Errorf("closed %d handles in observe-only mode", got)
+	}
+
+	// Without handles to close, the missing handles are reported instead
+	other := pf.AddProcess(process.FakeProcess{Name: d2r.ProcessName, StartTime: pf.Now()})
+	hf.SetHandles(other)
+	if _, err := e.CloseHandles(other); !errors.Is(err, handle.ErrNoHandles) {
+		t.Errorf("CloseHandles() without handles error = %v, want %v", err, handle.ErrNoHandles)
+	}
+}
+
+func TestLaunchQueueDryRun(t *testing.T) {
+	f := process.NewFake(time.Now())
+	cfg := config.Default()
+	e := New(Options{
+		Config:    &cfg,
+		Processes: f,
+		Handles:   handle.NewFake(),
+		DryRun:    true,
+		NewWatcher: func(names []string, _ time.Duration) (process.Watcher, error) {
+			return process.NewPollingWatcher(f, names, 10*time.Millisecond), nil
+		},
+	})
+	if err := e.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer e.Stop()
+
+	profiles := []config.LaunchProfile{
+		{Name: "first", Path: `C:\Games\D2R\D2R.exe`},
+		{Name: "second", Path: `C:\Games\D2R\D2R.exe`},
+	}
+	final := make(map[int]LaunchState)
+	err := e.LaunchQueue(context.Background(), profiles, func(p LaunchProgress) {
+		final[p.Index] = p.State
+	})
+	if !errors.Is(err, ErrDryRun) {
+		t.Fatalf("LaunchQueue() error = %v, want %v", err, ErrDryRun)
+	}
+	if got := len(f.Launches()); got != 0 {
+		t.Errorf("launched %d processes in observe-only mode", got)
+	}
+	for i := range profiles {
+		if final[i] != LaunchCancelled {
+			t.Errorf("entry %d ended %s, want %s", i, final[i], LaunchCancelled)
+		}
+	}
+}
@@ -0,0 +1,723 @@
+// Package engine implements the headless monitoring logic of Multiablo.
+// It closes the single-instance handles of the processes of every enabled
+// game, such as D2R, and manages launcher helpers such as Agent.exe,
+// reporting what it does through events and status snapshots so that any
+// frontend can subscribe to it.
+package engine
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/chenwei791129/multiablo/internal/config"
+	"github.com/chenwei791129/multiablo/internal/game"
+	"github.com/chenwei791129/multiablo/internal/handle"
+	"github.com/chenwei791129/multiablo/internal/i18n"
+	"github.com/chenwei791129/multiablo/internal/process"
+)
+
+const (
+	// subscriberBufferSize is the number of events buffered per subscriber
+	subscriberBufferSize = 64
+)
+
+// ErrDryRun is returned together with what would have been done when an
+// action is skipped in observe-only mode
+var ErrDryRun = errors.New("observe-only mode: nothing was changed")
+
+// ProcessStatus holds information about a monitored launcher helper process such as Agent.exe
+type ProcessStatus struct {
+	PID    uint32
+	Name   string
+	Uptime time.Duration
+
+	// ParentPID is the process that started this one
+	ParentPID uint32
+
+	// StartTime is the creation time of the process; it keeps a reused PID
+	// from being terminated. Zero when it could not be read.
+	StartTime time.Time
+
+	// Path and Args are the executable path and command-line arguments the
+	// process was started with, used to relaunch it
+	Path string
+	Args []string
+}
+
+// Status is a snapshot of the engine state
+type Status struct {
+	Running        bool
+	GameProcesses  []InstanceStatus
+	AgentProcesses []ProcessStatus
+	HandlesClosed  int
+	AgentsKilled   int
+
+	// Launchers are the running launchers, such as Battle.net.exe, of the enabled games
+	Launchers []ProcessStatus
+
+	// NameTimeouts counts handle name queries that timed out
+	NameTimeouts int
+
+	// NameRefusals counts handle name queries refused while earlier ones were stuck
+	NameRefusals int
+
+	// AgentsPaused is set when the agent strategy was paused because
+	// relaunched launcher helpers kept failing; AgentPauseHelper is the
+	// process name of the failing helper and AgentPauseErr the last failure
+	AgentsPaused     bool
+	AgentPauseHelper string
+	AgentPauseErr    error
+
+	// DryRun is set in observe-only mode
+	DryRun bool
+}
+
+// Options configures an Engine
+type Options struct {
+	// Config holds the intervals, thresholds and names to use; nil uses the defaults
+	Config *config.Config
+
+	// Processes is the process backend; nil uses the system backend
+	Processes process.Backend
+
+	// Handles is the handle backend; nil uses the system backend
+	Handles handle.Backend
+
+	// Games holds the known game definitions; nil uses the built-in definitions.
+	// The enabled_games setting selects the ones that are monitored.
+	Games *game.Registry
+
+	// AgentStrategy decides what happens to launcher helpers such as
+	// Agent.exe; nil uses the strategy selected by the agent_strategy setting
+	AgentStrategy AgentStrategy
+
+	// DryRun starts the engine in observe-only mode: processes and handles
+	// are discovered and reported, but no handle is closed and no process
+	// is terminated or launched
+	DryRun bool
+
+	// NewWatcher creates the process watcher used while the engine runs.
+	// Nil uses the system watcher when Processes is nil, falling back to
+	// polling Processes when the system watcher is unavailable.
+	NewWatcher func(names []string, interval time.Duration) (process.Watcher, error)
+}
+
+// Engine runs the handle closer and Agent.exe killer loops
+type Engine struct {
+	cfg       config.Config
+	processes process.Backend
+	handles   handle.Backend
+
+	// registry holds the known game definitions; games are the ones enabled in cfg
+	registry *game.Registry
+	games    gameSet
+
+	newWatcher func(names []string, interval time.Duration) (process.Watcher, error)
+	watcher    process.Watcher
+
+	// wakeInstances asks the handle closer loop to check game instances right away
+	wakeInstances chan struct{}
+
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+	running  bool
+
+	// Game instances reported by the watcher, including recently exited ones
+	instances map[InstanceKey]*instance
+
+	// Running launcher helper processes reported by the watcher, keyed by PID
+	agentProcesses map[uint32]ProcessStatus
+
+	// Running launchers reported by the watcher, keyed by PID
+	launchers map[uint32]ProcessStatus
+
+	// customStrategy is the strategy passed in the options, if any
+	customStrategy AgentStrategy
+
+	// launching counts the launches from launch profiles in progress
+	launching int
+
+	// relaunches are the relaunched helpers being supervised, keyed by the
+	// PID of the terminated helper. The agent strategy is paused when they keep failing.
+	relaunches       map[uint32]*relaunch
+	agentsPaused     bool
+	agentPauseHelper string
+	agentPauseErr    error
+
+	// dryRun is set in observe-only mode; observedAgents holds the helper
+	// PIDs already reported as due for termination in that mode
+	dryRun         bool
+	observedAgents map[uint32]bool
+
+	// Counters reported through Status
+	totalHandlesClosed int
+	totalAgentsKilled  int
+	totalNameTimeouts  int
+	totalNameRefusals  int
+
+	// Event subscribers
+	subscribers map[int]chan Event
+	nextSubID   int
+
+	mu sync.Mutex
+}
+
+// New creates a new engine instance
+func New(opts Options) *Engine {
+	cfg := config.Default()
+	if opts.Config != nil {
+		cfg = *opts.Config
+	}
+	if opts.NewWatcher == nil {
+		opts.NewWatcher = defaultWatcher(opts.Processes)
+	}
+	if opts.Processes == nil {
+		opts.Processes = process.NewSystemBackend()
+	}
+	if opts.Handles == nil {
+		opts.Handles = handle.NewSystemBackend()
+	}
+	if opts.Games == nil {
+		opts.Games = game.BuiltinRegistry()
+	}
+
+	return &Engine{
+		cfg:            cfg,
+		registry:       opts.Games,
+		games:          gamesFor(cfg, opts.Games),
+		processes:      opts.Processes,
+		handles:        opts.Handles,
+		newWatcher:     opts.NewWatcher,
+		wakeInstances:  make(chan struct{}, 1),
+		instances:      make(map[InstanceKey]*instance),
+		agentProcesses: make(map[uint32]ProcessStatus),
+		launchers:      make(map[uint32]ProcessStatus),
+		customStrategy: opts.AgentStrategy,
+		relaunches:     make(map[uint32]*relaunch),
+		dryRun:         opts.DryRun,
+		observedAgents: make(map[uint32]bool),
+		subscribers:    make(map[int]chan Event),
+	}
+}
+
+// defaultWatcher returns the watcher factory for a process backend:
+// the system watcher for the system backend, polling for any other backend
+func defaultWatcher(backend process.Backend) func([]string, time.Duration) (process.Watcher, error) {
+	if backend != nil {
+		return func(names []string, interval time.Duration) (process.Watcher, error) {
+			return process.NewPollingWatcher(backend, names, interval), nil
+		}
+	}
+	return func(names []string, interval time.Duration) (process.Watcher, error) {
+		w, err := process.NewSystemWatcher(names, interval)
+		if err != nil {
+			return process.NewPollingWatcher(process.NewSystemBackend(), names, interval), nil
+		}
+		return w, nil
+	}
+}
+
+// Start begins the monitoring loops for the enabled games.
+// It fails when none of them is defined, when this build cannot manage the
+// handles of the system (handle.ErrArchitectureMismatch) or when no process
+// watcher can be created.
+func (e *Engine) Start() error {
+	e.mu.Lock()
+	if e.running {
+		e.mu.Unlock()
+		return nil
+	}
+
+	games := e.games
+	if len(games.games) == 0 {
+		e.mu.Unlock()
+		return ErrNoGames
+	}
+
+	// Every handle operation would fail, so refuse to start at all
+	if err := e.handles.CheckArchitecture(); err != nil {
+		e.mu.Unlock()
+		return err
+	}
+
+	watcher, err := e.newWatcher(games.watchedNames(), e.cfg.ProcessPollInterval.Std())
+	if err != nil {
+		e.mu.Unlock()
+		return fmt.Errorf("failed to watch processes: %w", err)
+	}
+
+	// The watcher reports every running process as started again
+	e.running = true
+	e.watcher = watcher
+	e.stopChan = make(chan struct{})
+	clear(e.instances)
+	clear(e.agentProcesses)
+	clear(e.launchers)
+	clear(e.observedAgents)
+	clear(e.relaunches)
+	e.agentsPaused = false
+	e.agentPauseHelper = ""
+	e.agentPauseErr = nil
+	e.mu.Unlock()
+
+	for _, id := range games.unknown {
+		e.emit(Event{
+			Type:    EventError,
+			Err:     fmt.Errorf("unknown game %q", id),
+			Message: fmt.Sprintf(i18n.Get("Game %q is enabled but not defined"), id),
+		})
+	}
+
+	e.wg.Add(3)
+	go func() {
+		defer e.wg.Done()
+		e.watchLoop(watcher.Events())
+	}()
+	go func() {
+		defer e.wg.Done()
+		e.handleCloserLoop()
+	}()
+	go func() {
+		defer e.wg.Done()
+		e.agentKillerLoop()
+	}()
+	return nil
+}
+
+// Stop stops the monitoring loops and waits for them to finish
+func (e *Engine) Stop() {
+	e.mu.Lock()
+	if !e.running {
+		e.mu.Unlock()
+		return
+	}
+	e.running = false
+	close(e.stopChan)
+	watcher := e.watcher
+	e.watcher = nil
+	e.mu.Unlock()
+
+	watcher.Close()
+	e.wg.Wait()
+}
+
+// IsRunning returns whether the engine is running
+func (e *Engine) IsRunning() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.running
+}
+
+// SetConfig replaces the configuration of the engine.
+// Running loops pick up changed intervals after their next tick and
+// changed handle match rules on their next scan. The process watcher is
+// switched to the names of the enabled games right away: processes of
+// newly enabled games are detected at its next poll, and those of disabled
+// games are no longer tracked. The process poll interval is only read by Start.
+func (e *Engine) SetConfig(cfg config.Config) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.cfg = cfg
+	e.games = gamesFor(cfg, e.registry)
+	if e.watcher == nil {
+		return
+	}
+
+	e.watcher.SetNames(e.games.watchedNames())
+	for key, inst := range e.instances {
+		if !e.games.isWatched(inst.ProcessName) {
+			delete(e.instances, key)
+		}
+	}
+	for _, processes := range []map[uint32]ProcessStatus{e.agentProcesses, e.launchers} {
+		for pid, p := range processes {
+			if !e.games.isWatched(p.Name) {
+				delete(processes, pid)
+			}
+		}
+	}
+	for key, r := range e.relaunches {
+		if !e.games.isWatched(r.name) {
+			delete(e.relaunches, key)
+		}
+	}
+}
+
+// SetDryRun turns observe-only mode on or off. Instances that were only
+// observed are scanned again, for real, when it is turned off.
+func (e *Engine) SetDryRun(dryRun bool) {
+	e.mu.Lock()
+	e.dryRun = dryRun
+	clear(e.observedAgents)
+	rescan := false
+	if !dryRun {
+		for _, inst := range e.instances {
+			if inst.State == InstanceObserved {
+				inst.State = InstanceDetected
+				rescan = true
+			}
+		}
+	}
+	e.mu.Unlock()
+
+	if rescan {
+		e.wake()
+	}
+}
+
+// DryRun reports whether the engine is in observe-only mode
+func (e *Engine) DryRun() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.dryRun
+}
+
+// handleMatcher returns the matcher of all enabled games, or ErrNoGames
+func (e *Engine) handleMatcher() (*handle.Matcher, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.games.matcher == nil {
+		return nil, ErrNoGames
+	}
+	return e.games.matcher, nil
+}
+
+// settings returns the configuration in effect
+func (e *Engine) settings() config.Config {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.cfg
+}
+
+// Status returns a snapshot of the current engine state
+func (e *Engine) Status() Status {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return Status{
+		Running:          e.running,
+		GameProcesses:    e.instanceStatuses(),
+		AgentProcesses:   sortedStatuses(e.agentProcesses),
+		Launchers:        sortedStatuses(e.launchers),
+		HandlesClosed:    e.totalHandlesClosed,
+		AgentsKilled:     e.totalAgentsKilled,
+		NameTimeouts:     e.totalNameTimeouts,
+		NameRefusals:     e.totalNameRefusals,
+		AgentsPaused:     e.agentsPaused,
+		AgentPauseHelper: e.agentPauseHelper,
+		AgentPauseErr:    e.agentPauseErr,
+		DryRun:           e.dryRun,
+	}
+}
+
+// Subscribe registers a new event subscriber.
+// Events are delivered on the returned channel until the returned cancel
+// function is called, which also closes the channel. A subscriber that does
+// not keep up misses events instead of blocking the engine.
+func (e *Engine) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBufferSize)
+
+	e.mu.Lock()
+	id := e.nextSubID
+	e.nextSubID++
+	e.subscribers[id] = ch
+	e.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			e.mu.Lock()
+			delete(e.subscribers, id)
+			e.mu.Unlock()
+			close(ch)
+		})
+	}
+
+	return ch, cancel
+}
+
+// emit delivers an event to all subscribers
+func (e *Engine) emit(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, ch := range e.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// Subscriber buffer full, skip this event
+		}
+	}
+}
+
+// watchLoop tracks the processes reported by the watcher and wakes the
+// handle closer loop as soon as a new game process is reported
+func (e *Engine) watchLoop(events <-chan process.WatchEvent) {
+	for {
+		select {
+		case <-e.stopChan:
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			switch event.Type {
+			case process.ProcessStarted:
+				e.processStarted(event.Process)
+			case process.ProcessExited:
+				e.processExited(event.Process)
+			}
+		}
+	}
+}
+
+// processStarted starts tracking a new game, launcher helper or launcher process
+func (e *Engine) processStarted(proc process.ProcessInfo) {
+	games := e.enabledGames()
+	g, isGame := games.gameOf(proc.Name)
+	h, isHelper := games.helper(proc.Name)
+	isLauncher := games.isLauncher(proc.Name)
+
+	switch {
+	case isGame:
+		e.trackInstance(proc, g.ID)
+	case isHelper:
+		// Remember how the helper was started while it still runs
+		status := e.helperStatus(h, proc)
+		e.mu.Lock()
+		e.agentProcesses[proc.PID] = status
+		e.mu.Unlock()
+		e.relaunchSeen(proc.PID)
+	case isLauncher:
+		e.mu.Lock()
+		e.launchers[proc.PID] = ProcessStatus{
+			PID:       proc.PID,
+			Name:      proc.Name,
+			ParentPID: proc.ParentPID,
+			StartTime: proc.CreationTime,
+			Path:      proc.Path,
+			Args:      proc.Args(),
+		}
+		e.mu.Unlock()
+	default:
+		// The game of the process was disabled after the watcher listed it
+		return
+	}
+
+	e.emit(Event{
+		Type:        EventProcessAppeared,
+		ProcessName: proc.Name,
+		PID:         proc.PID,
+		Message:     fmt.Sprintf(i18n.Get("Detected %s (PID: %d)"), proc.Name, proc.PID),
+	})
+
+	if isGame {
+		e.wake()
+	}
+}
+
+// wake asks the handle closer loop to check the instances right away
+func (e *Engine) wake() {
+	select {
+	case e.wakeInstances <- struct{}{}:
+	default:
+		// A check is already pending
+	}
+}
+
+// processExited stops tracking a game, launcher helper or launcher process
+func (e *Engine) processExited(proc process.ProcessInfo) {
+	e.instanceExited(proc.PID)
+
+	e.mu.Lock()
+	delete(e.agentProcesses, proc.PID)
+	delete(e.launchers, proc.PID)
+	delete(e.observedAgents, proc.PID)
+	e.mu.Unlock()
+
+	e.emit(Event{
+		Type:        EventProcessExited,
+		ProcessName: proc.Name,
+		PID:         proc.PID,
+		Message:     fmt.Sprintf(i18n.Get("%s (PID: %d) exited"), proc.Name, proc.PID),
+	})
+}
+
+// handleCloserLoop scans new game instances as soon as the watcher reports them
+// and instances that are due for a retry every interval
+func (e *Engine) handleCloserLoop() {
+	interval := e.settings().HandleCheckInterval.Std()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stopChan:
+			return
+		case <-e.wakeInstances:
+			e.checkInstances()
+		case <-ticker.C:
+			e.checkInstances()
+
+			// Follow interval changes made through SetConfig
+			if next := e.settings().HandleCheckInterval.Std(); next != interval {
+				interval = next
+				ticker.Reset(interval)
+			}
+		}
+	}
+}
+
+// checkInstances scans the game instances that need it. Verified and
+// failed instances are not scanned again. All due instances share one
+// handle snapshot, and one more snapshot verifies those that closed handles.
+func (e *Engine) checkInstances() {
+	due := e.dueInstances()
+	if len(due) == 0 {
+		return
+	}
+
+	snapshot, err := e.instanceSnapshot(instancePIDs(due))
+	if err != nil {
+		for _, key := range due {
+			e.retryInstance(key, fmt.Errorf("failed to find handles: %w", err))
+		}
+		return
+	}
+
+	var closed []InstanceKey
+	for _, key := range due {
+		if e.scanInstance(key, snapshot) {
+			closed = append(closed, key)
+		}
+	}
+	if len(closed) > 0 {
+		e.verifyInstances(closed, snapshot)
+	}
+}
+
+// agentKillerLoop continuously monitors and kills launcher helper processes such as Agent.exe
+func (e *Engine) agentKillerLoop() {
+	interval := e.settings().AgentCheckInterval.Std()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stopChan:
+			return
+		case <-ticker.C:
+			e.checkAgentProcesses()
+
+			// Follow interval changes made through SetConfig
+			if next := e.settings().AgentCheckInterval.Std(); next != interval {
+				interval = next
+				ticker.Reset(interval)
+			}
+		}
+	}
+}
+
+// checkAgentProcesses updates the uptime of the tracked launcher helper
+// processes and kills, and possibly relaunches, each helper the agent
+// strategy decides on
+func (e *Engine) checkAgentProcesses() {
+	e.mu.Lock()
+	pids := make([]uint32, 0, len(e.agentProcesses))
+	for pid := range e.agentProcesses {
+		pids = append(pids, pid)
+	}
+	e.mu.Unlock()
+
+	for _, pid := range pids {
+		uptime, err := e.processes.GetProcessUptime(pid)
+		if err != nil {
+			continue
+		}
+
+		e.mu.Lock()
+		if p, ok := e.agentProcesses[pid]; ok {
+			p.Uptime = uptime
+			e.agentProcesses[pid] = p
+		}
+		e.mu.Unlock()
+	}
+
+	// Confirm or retry earlier relaunches; nothing is done while paused
+	e.checkRelaunches()
+	if len(pids) == 0 || e.AgentsPaused() {
+		return
+	}
+
+	strategy := e.agentStrategy()
+	state := e.agentState()
+	actions := make(map[uint32]AgentAction)
+	var targets []uint32
+	for _, p := range state.Helpers {
+		if action := strategy.Decide(state, p); action != AgentKeep {
+			actions[p.PID] = action
+			targets = append(targets, p.PID)
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	// In observe-only mode, report the helpers once instead of every interval
+	if !e.observeAgents(targets) {
+		return
+	}
+
+	// Kill each helper and relaunch it the way it was started
+	e.settleRelaunches()
+	for _, p := range state.Helpers {
+		action, ok := actions[p.PID]
+		if !ok {
+			continue
+		}
+		err := e.killAgent(p)
+		if (err == nil || errors.Is(err, ErrDryRun)) && action == AgentKillRelaunch {
+			e.superviseRelaunch(p)
+		}
+	}
+}
+
+// observeAgents reports whether the helpers with the given PIDs should be
+// handled. Outside observe-only mode they always are; in observe-only mode
+// only when one of them has not been reported yet.
+func (e *Engine) observeAgents(pids []uint32) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.dryRun {
+		return true
+	}
+	fresh := false
+	for _, pid := range pids {
+		if !e.observedAgents[pid] {
+			e.observedAgents[pid] = true
+			fresh = true
+		}
+	}
+	return fresh
+}
+
+// sortedStatuses returns the process statuses ordered by PID
+func sortedStatuses(processes map[uint32]ProcessStatus) []ProcessStatus {
+	statuses := make([]ProcessStatus, 0, len(processes))
+	for _, p := range processes {
+		statuses = append(statuses, p)
+	}
+	slices.SortFunc(statuses, func(a, b ProcessStatus) int {
+		return cmp.Compare(a.PID, b.PID)
+	})
+	return statuses
+}
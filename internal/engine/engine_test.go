@@ -0,0 +1,171 @@
+package engine
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/chenwei791129/multiablo/internal/config"
+	"github.com/chenwei791129/multiablo/internal/game"
+	"github.com/chenwei791129/multiablo/internal/handle"
+	"github.com/chenwei791129/multiablo/internal/process"
+	"github.com/chenwei791129/multiablo/pkg/d2r"
+)
+
+// waitFor polls cond until it holds, failing the test after a few seconds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// hasInstance reports whether the status tracks a running instance with the given PID
+func hasInstance(status Status, pid uint32) bool {
+	for _, inst := range status.GameProcesses {
+		if inst.PID == pid && inst.State != InstanceExited {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSetConfigWatchesEnabledGames(t *testing.T) {
+	other := game.Definition{
+		ID:           "other",
+		ProcessNames: []string{"Other.exe"},
+		HandleMatchRules: []handle.MatchRule{{
+			Type:    "Mutant",
+			Match:   handle.MatchExact,
+			Pattern: "Other Single Instance",
+		}},
+	}
+	registry, err := game.NewRegistry(append(game.Builtin(), other))
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+
+	f := process.NewFake(time.Now())
+	pid := f.AddProcess(process.FakeProcess{Name: "Other.exe", StartTime: f.Now()})
+
+	cfg := config.Default()
+	cfg.EnabledGames = []string{game.D2RID}
+	e := New(Options{
+		Config:    &cfg,
+		Processes: f,
+		Handles:   handle.NewFake(),
+		Games:     registry,
+		NewWatcher: func(names []string, _ time.Duration) (process.Watcher, error) {
+			return process.NewPollingWatcher(f, names, 10*time.Millisecond), nil
+		},
+	})
+	if err := e.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer e.Stop()
+
+	time.Sleep(50 * time.Millisecond)
+	if hasInstance(e.Status(), pid) {
+		t.Fatal("Other.exe is tracked before its game is enabled")
+	}
+
+	enabled := cfg
+	enabled.EnabledGames = []string{game.D2RID, other.ID}
+	e.SetConfig(enabled)
+	waitFor(t, "Other.exe to be tracked", func() bool {
+		return hasInstance(e.Status(), pid)
+	})
+
+	e.SetConfig(cfg)
+	if hasInstance(e.Status(), pid) {
+		t.Error("Other.exe is still tracked after its game was disabled")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if hasInstance(e.Status(), pid) {
+		t.Error("Other.exe is tracked again after its game was disabled")
+	}
+}
+
+func TestCheckInstancesSharesSnapshots(t *testing.T) {
+	const instanceCount = 4
+
+	pf := process.NewFake(time.Now())
+	hf := handle.NewFake()
+	e := New(Options{Processes: pf, Handles: hf})
+
+	// track adds running instances, with the single-instance handle if handles is set
+	track := func(handles bool) []uint32 {
+		pids := make([]uint32, instanceCount)
+		for i := range pids {
+			pids[i] = pf.AddProcess(process.FakeProcess{Name: d2r.ProcessName, StartTime: pf.Now()})
+			if handles {
+				hf.SetHandles(pids[i], testSingleInstance)
+			} else {
+				hf.SetHandles(pids[i])
+			}
+			e.trackInstance(process.ProcessInfo{PID: pids[i], Name: d2r.ProcessName, CreationTime: pf.Now()}, game.D2RID)
+		}
+		return pids
+	}
+	states := func(pids []uint32) map[InstanceState]int {
+		count := make(map[InstanceState]int)
+		for _, inst := range e.Status().GameProcesses {
+			if slices.Contains(pids, inst.PID) {
+				count[inst.State]++
+			}
+		}
+		return count
+	}
+
+	// One snapshot finds the handles of every instance and one more verifies them all
+	closing := track(true)
+	e.checkInstances()
+	if got := hf.Snapshots(); got != 2 {
+		t.Errorf("closing %d handles took %d snapshots, want 2", instanceCount, got)
+	}
+	if got := len(hf.Closed()); got != instanceCount {
+		t.Errorf("closed %d handles, want %d", got, instanceCount)
+	}
+	if got := states(closing)[InstanceVerified]; got != instanceCount {
+		t.Errorf("%d instances verified, want %d", got, instanceCount)
+	}
+
+	// New instances without the handle yet are all scanned from one snapshot
+	waiting := track(false)
+	e.checkInstances()
+	if got := hf.Snapshots() - 2; got != 1 {
+		t.Errorf("scanning %d new instances took %d snapshots, want 1", instanceCount, got)
+	}
+	if got := states(waiting)[InstanceRetrying]; got != instanceCount {
+		t.Errorf("%d instances wait for their handle, want %d", got, instanceCount)
+	}
+}
+
+func TestStartArchitectureMismatch(t *testing.T) {
+	hf := handle.NewFake()
+	hf.SetArchitectureError(handle.ErrArchitectureMismatch)
+	watchers := 0
+	e := New(Options{
+		Processes: process.NewFake(time.Now()),
+		Handles:   hf,
+		NewWatcher: func(names []string, interval time.Duration) (process.Watcher, error) {
+			watchers++
+			return nil, nil
+		},
+	})
+
+	if err := e.Start(); !errors.Is(err, handle.ErrArchitectureMismatch) {
+		t.Fatalf("Start() error = %v, want %v", err, handle.ErrArchitectureMismatch)
+	}
+	if e.IsRunning() {
+		t.Error("IsRunning() = true after a failed start")
+	}
+	if watchers != 0 {
+		t.Errorf("created %d watchers, want none", watchers)
+	}
+}
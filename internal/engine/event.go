@@ -0,0 +1,83 @@
+package engine
+
+import (
+	"time"
+)
+
+// EventType identifies the kind of an engine event
+type EventType int
+
+const (
+	// EventProcessAppeared is emitted when a monitored process is seen for the first time
+	EventProcessAppeared EventType = iota
+	// EventHandleClosed is emitted after single-instance handles were closed in a game process
+	EventHandleClosed
+	// EventAgentKilled is emitted after launcher helper processes such as Agent.exe were terminated
+	EventAgentKilled
+	// EventAgentRelaunched is emitted after a launcher helper such as Agent.exe was started again
+	EventAgentRelaunched
+	// EventError is emitted when an operation fails
+	EventError
+	// EventProcessLaunched is emitted after D2R was started from a launch profile
+	EventProcessLaunched
+	// EventProcessExited is emitted when a monitored process is gone
+	EventProcessExited
+	// EventHandleBlocked is emitted when a single-instance object still
+	// exists after its handles were closed, because another process holds it
+	EventHandleBlocked
+	// EventAgentPaused is emitted when the agent strategy is paused because
+	// relaunched launcher helpers kept failing to start
+	EventAgentPaused
+	// EventAgentResumed is emitted when a paused agent strategy is resumed
+	EventAgentResumed
+)
+
+// String returns the name of the event type
+func (t EventType) String() string {
+	switch t {
+	case EventProcessAppeared:
+		return "process_appeared"
+	case EventHandleClosed:
+		return "handle_closed"
+	case EventAgentKilled:
+		return "agent_killed"
+	case EventAgentRelaunched:
+		return "agent_relaunched"
+	case EventError:
+		return "error"
+	case EventProcessLaunched:
+		return "process_launched"
+	case EventProcessExited:
+		return "process_exited"
+	case EventHandleBlocked:
+		return "handle_blocked"
+	case EventAgentPaused:
+		return "agent_paused"
+	case EventAgentResumed:
+		return "agent_resumed"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes something the engine observed or did.
+// Fields that do not apply to an event type are left at their zero value.
+type Event struct {
+	Type        EventType
+	Time        time.Time
+	ProcessName string
+	PID         uint32
+	Count       int
+	Path        string
+	Err         error
+
+	// BlockedBy lists the processes holding a single-instance object that
+	// should be gone, for EventHandleBlocked; it is empty when they are unknown
+	BlockedBy []uint32
+
+	// DryRun marks events that describe what would have been done in observe-only mode
+	DryRun bool
+
+	// Message is a localized, human-readable description of the event
+	Message string
+}
@@ -0,0 +1,182 @@
+package engine
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/chenwei791129/multiablo/internal/config"
+	"github.com/chenwei791129/multiablo/internal/game"
+	"github.com/chenwei791129/multiablo/internal/handle"
+)
+
+// ErrNoGames is returned when none of the enabled games is defined
+var ErrNoGames = errors.New("none of the enabled games is defined")
+
+// gameEntry is an enabled game definition with its compiled handle matcher
+type gameEntry struct {
+	game.Definition
+	matcher *handle.Matcher
+}
+
+// gameSet holds the enabled games of a configuration
+type gameSet struct {
+	games []gameEntry
+
+	// matcher combines the rules of all games; nil when no game is enabled
+	matcher *handle.Matcher
+
+	// unknown lists the enabled IDs that are not defined
+	unknown []string
+}
+
+// gamesFor compiles the games enabled in cfg. A D2R definition whose rules
+// from cfg are invalid falls back to the rules of the registry.
+func gamesFor(cfg config.Config, registry *game.Registry) gameSet {
+	definitions, unknown := cfg.Games(registry)
+	set := gameSet{unknown: unknown}
+
+	var rules []handle.MatchRule
+	for _, d := range definitions {
+		matcher, err := handle.NewMatcher(d.HandleMatchRules)
+		if err != nil {
+			d, _ = registry.Get(d.ID)
+			matcher, _ = handle.NewMatcher(d.HandleMatchRules)
+		}
+		set.games = append(set.games, gameEntry{Definition: d, matcher: matcher})
+		rules = append(rules, d.HandleMatchRules...)
+	}
+
+	if len(rules) > 0 {
+		set.matcher, _ = handle.NewMatcher(rules)
+	}
+	return set
+}
+
+// game returns the enabled game with the given ID
+func (s gameSet) game(id string) (gameEntry, bool) {
+	for _, g := range s.games {
+		if g.ID == id {
+			return g, true
+		}
+	}
+	return gameEntry{}, false
+}
+
+// gameOf returns the enabled game a process name belongs to
+func (s gameSet) gameOf(processName string) (gameEntry, bool) {
+	for _, g := range s.games {
+		if g.HasProcess(processName) {
+			return g, true
+		}
+	}
+	return gameEntry{}, false
+}
+
+// processNames returns the game process names of all enabled games
+func (s gameSet) processNames() []string {
+	var names []string
+	for _, g := range s.games {
+		for _, name := range g.ProcessNames {
+			names = appendName(names, name)
+		}
+	}
+	return names
+}
+
+// helpers returns the launcher helpers of all enabled games, one per process name
+func (s gameSet) helpers() []game.Helper {
+	var helpers []game.Helper
+	for _, g := range s.games {
+		for _, h := range g.Helpers {
+			if !slices.ContainsFunc(helpers, func(other game.Helper) bool {
+				return strings.EqualFold(other.ProcessName, h.ProcessName)
+			}) {
+				helpers = append(helpers, h)
+			}
+		}
+	}
+	return helpers
+}
+
+// launcherNames returns the launcher process names of all enabled games
+func (s gameSet) launcherNames() []string {
+	var names []string
+	for _, g := range s.games {
+		for _, name := range g.Launchers {
+			names = appendName(names, name)
+		}
+	}
+	return names
+}
+
+// isLauncher reports whether a process name is a launcher of an enabled game
+func (s gameSet) isLauncher(processName string) bool {
+	for _, g := range s.games {
+		if g.HasLauncher(processName) {
+			return true
+		}
+	}
+	return false
+}
+
+// helper returns the launcher helper with the given process name
+func (s gameSet) helper(processName string) (game.Helper, bool) {
+	for _, g := range s.games {
+		if h, ok := g.Helper(processName); ok {
+			return h, true
+		}
+	}
+	return game.Helper{}, false
+}
+
+// watchedNames returns the game, helper and launcher process names of all
+// enabled games, which the process watcher reports
+func (s gameSet) watchedNames() []string {
+	names := s.processNames()
+	for _, h := range s.helpers() {
+		names = appendName(names, h.ProcessName)
+	}
+	for _, name := range s.launcherNames() {
+		names = appendName(names, name)
+	}
+	return names
+}
+
+// isWatched reports whether a process name is watched for the enabled games
+func (s gameSet) isWatched(name string) bool {
+	_, isGame := s.gameOf(name)
+	_, isHelper := s.helper(name)
+	return isGame || isHelper || s.isLauncher(name)
+}
+
+// appendName appends a process name unless it is already listed (case-insensitive)
+func appendName(names []string, name string) []string {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return names
+		}
+	}
+	return append(names, name)
+}
+
+// enabledGames returns the games in effect
+func (e *Engine) enabledGames() gameSet {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.games
+}
+
+// instanceMatcher returns the matcher of the game an instance belongs to,
+// or the matcher of all games when that game is no longer enabled
+func (e *Engine) instanceMatcher(key InstanceKey) *handle.Matcher {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if inst, ok := e.instances[key]; ok {
+		if g, ok := e.games.game(inst.Game); ok {
+			return g.matcher
+		}
+	}
+	return e.games.matcher
+}
@@ -0,0 +1,368 @@
+package engine
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/chenwei791129/multiablo/internal/handle"
+	"github.com/chenwei791129/multiablo/internal/i18n"
+	"github.com/chenwei791129/multiablo/internal/process"
+)
+
+const (
+	// maxRetryDelay caps the backoff between scans of a game instance
+	maxRetryDelay = 30 * time.Second
+
+	// maxScanAttempts is how often a failing scan is tried before giving up
+	maxScanAttempts = 8
+
+	// handleGracePeriod is how long a new game instance has to create its
+	// single-instance handle; an older instance without one is verified
+	handleGracePeriod = 30 * time.Second
+
+	// exitedRetention is how long an exited instance stays in the status
+	exitedRetention = 10 * time.Second
+)
+
+// InstanceState is the lifecycle state of a game instance
+type InstanceState int
+
+const (
+	// InstanceDetected means the instance was found and has not been scanned yet
+	InstanceDetected InstanceState = iota
+	// InstanceScanning means the instance's handles are being enumerated and closed
+	InstanceScanning
+	// InstanceHandleClosed means the single-instance handle was closed and awaits verification
+	InstanceHandleClosed
+	// InstanceVerified means the instance holds no single-instance handle; it is not scanned again
+	InstanceVerified
+	// InstanceRetrying means a scan did not succeed and is repeated after a backoff
+	InstanceRetrying
+	// InstanceFailed means scanning failed too often; the instance is not scanned again
+	InstanceFailed
+	// InstanceExited means the process is gone
+	InstanceExited
+	// InstanceObserved means the single-instance handle was found in observe-only
+	// mode and left open; the instance is scanned again when the mode is turned off
+	InstanceObserved
+)
+
+// String returns the name of the instance state
+func (s InstanceState) String() string {
+	switch s {
+	case InstanceDetected:
+		return "detected"
+	case InstanceScanning:
+		return "scanning"
+	case InstanceHandleClosed:
+		return "handle_closed"
+	case InstanceVerified:
+		return "verified"
+	case InstanceRetrying:
+		return "retrying"
+	case InstanceFailed:
+		return "failed"
+	case InstanceExited:
+		return "exited"
+	case InstanceObserved:
+		return "observed"
+	default:
+		return "unknown"
+	}
+}
+
+// InstanceKey identifies a game instance. The creation time tells apart
+// processes that were given the same PID.
+type InstanceKey struct {
+	PID     uint32
+	Created time.Time
+}
+
+// InstanceStatus describes the state of a game instance
+type InstanceStatus struct {
+	InstanceKey
+	ProcessName string
+
+	// Game is the ID of the game definition the instance belongs to
+	Game string
+
+	// ParentPID is the process that started the instance, e.g. Battle.net.exe
+	ParentPID uint32
+
+	State InstanceState
+
+	// Attempts counts the scans that did not succeed
+	Attempts int
+
+	// Err is the reason of the last unsuccessful scan
+	Err error
+}
+
+// instance is the tracked state of a game instance
+type instance struct {
+	InstanceStatus
+
+	detected  time.Time
+	nextCheck time.Time
+	exitedAt  time.Time
+}
+
+// trackInstance starts tracking a newly detected process of a game
+func (e *Engine) trackInstance(proc process.ProcessInfo, gameID string) {
+	pid := proc.PID
+	created := proc.CreationTime
+	if created.IsZero() {
+		created, _ = e.processes.GetProcessCreationTime(pid)
+	}
+	key := InstanceKey{PID: pid, Created: created}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// A running instance with the same PID but another creation time was missed exiting
+	for k, inst := range e.instances {
+		if k.PID == pid && k != key && inst.State != InstanceExited {
+			inst.State = InstanceExited
+			inst.exitedAt = time.Now()
+		}
+	}
+
+	if _, ok := e.instances[key]; ok {
+		return
+	}
+	e.instances[key] = &instance{
+		InstanceStatus: InstanceStatus{
+			InstanceKey: key,
+			ProcessName: proc.Name,
+			Game:        gameID,
+			ParentPID:   proc.ParentPID,
+			State:       InstanceDetected,
+		},
+		detected: time.Now(),
+	}
+}
+
+// instanceExited marks the running game instance with the given PID as exited
+func (e *Engine) instanceExited(pid uint32) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for k, inst := range e.instances {
+		if k.PID == pid && inst.State != InstanceExited {
+			inst.State = InstanceExited
+			inst.exitedAt = time.Now()
+		}
+	}
+}
+
+// dueInstances marks the instances that need a scan as scanning and returns
+// them, forgetting exited instances after their retention time
+func (e *Engine) dueInstances() []InstanceKey {
+	now := time.Now()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var due []InstanceKey
+	for key, inst := range e.instances {
+		switch inst.State {
+		case InstanceDetected:
+		case InstanceRetrying:
+			if now.Before(inst.nextCheck) {
+				continue
+			}
+		case InstanceExited:
+			if now.Sub(inst.exitedAt) > exitedRetention {
+				delete(e.instances, key)
+			}
+			continue
+		default:
+			continue
+		}
+
+		inst.State = InstanceScanning
+		due = append(due, key)
+	}
+	return due
+}
+
+// scanInstance closes the single-instance handles that a snapshot lists for
+// an instance and moves the instance to its next state. It reports whether
+// handles were closed, in which case the instance still needs verification.
+func (e *Engine) scanInstance(key InstanceKey, snapshot *handle.Snapshot) bool {
+	matcher := e.instanceMatcher(key)
+	closedCount, err := e.closeSnapshotHandles(snapshot, key.PID, e.instanceName(key), matcher)
+	switch {
+	case err == nil && closedCount > 0:
+		e.setInstanceState(key, InstanceHandleClosed, nil)
+		return true
+
+	case errors.Is(err, ErrDryRun):
+		e.setInstanceState(key, InstanceObserved, nil)
+
+	case errors.Is(err, handle.ErrNoHandles):
+		// A handle whose name is unknown may still be the single-instance handle
+		if unresolved := snapshot.Unresolved(key.PID, matcher.Types()); unresolved > 0 {
+			e.retryInstance(key, fmt.Errorf("the names of %d handle(s) could not be resolved", unresolved))
+			return false
+		}
+		// A new instance may not have created its handle yet
+		if e.instanceAge(key) < handleGracePeriod {
+			e.awaitHandle(key)
+			return false
+		}
+		e.setInstanceState(key, InstanceVerified, nil)
+
+	default:
+		e.retryInstance(key, err)
+	}
+	return false
+}
+
+// verifyInstances takes another snapshot of the given instances to confirm
+// that no single-instance handle is left; before is the snapshot their
+// handles were closed from
+func (e *Engine) verifyInstances(keys []InstanceKey, before *handle.Snapshot) {
+	snapshot, err := e.instanceSnapshot(instancePIDs(keys))
+	if err != nil {
+		for _, key := range keys {
+			e.retryInstance(key, fmt.Errorf("failed to verify: %w", err))
+		}
+		return
+	}
+
+	for _, key := range keys {
+		if err := e.verifyClosed(before, snapshot, key.PID, e.instanceName(key), e.instanceMatcher(key)); err != nil {
+			e.retryInstance(key, err)
+			continue
+		}
+		e.setInstanceState(key, InstanceVerified, nil)
+	}
+}
+
+// retryInstance schedules another scan with exponential backoff.
+// When the attempt limit is reached, the instance fails instead.
+func (e *Engine) retryInstance(key InstanceKey, err error) {
+	baseDelay := e.settings().HandleCheckInterval.Std()
+
+	e.mu.Lock()
+	inst, ok := e.instances[key]
+	if !ok || inst.State == InstanceExited {
+		e.mu.Unlock()
+		return
+	}
+
+	inst.Attempts++
+	inst.Err = err
+	if inst.Attempts >= maxScanAttempts {
+		inst.State = InstanceFailed
+		attempts := inst.Attempts
+		name := inst.ProcessName
+		e.mu.Unlock()
+
+		e.emit(Event{
+			Type:        EventError,
+			ProcessName: name,
+			PID:         key.PID,
+			Err:         err,
+			Message:     fmt.Sprintf(i18n.Get("Gave up on %s (PID: %d) after %d attempts: %v"), name, key.PID, attempts, err),
+		})
+		return
+	}
+
+	inst.State = InstanceRetrying
+	inst.nextCheck = time.Now().Add(retryDelay(baseDelay, inst.Attempts))
+	e.mu.Unlock()
+}
+
+// awaitHandle schedules another scan of a new instance that has not created
+// its single-instance handle yet after handle_check_interval. The wait is
+// not a failed attempt, so it neither backs off nor counts towards the limit.
+func (e *Engine) awaitHandle(key InstanceKey) {
+	interval := e.settings().HandleCheckInterval.Std()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	inst, ok := e.instances[key]
+	if !ok || inst.State == InstanceExited {
+		return
+	}
+	inst.State = InstanceRetrying
+	inst.Err = nil
+	inst.nextCheck = time.Now().Add(interval)
+}
+
+// setInstanceState moves an instance that is still running to a new state
+func (e *Engine) setInstanceState(key InstanceKey, state InstanceState, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	inst, ok := e.instances[key]
+	if !ok || inst.State == InstanceExited {
+		return
+	}
+	inst.State = state
+	inst.Err = err
+}
+
+// instanceAge returns how long an instance has been running, falling back to
+// the time since it was detected when the process cannot be queried
+func (e *Engine) instanceAge(key InstanceKey) time.Duration {
+	if uptime, err := e.processes.GetProcessUptime(key.PID); err == nil {
+		return uptime
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if inst, ok := e.instances[key]; ok {
+		return time.Since(inst.detected)
+	}
+	return 0
+}
+
+// instanceName returns the process name of an instance
+func (e *Engine) instanceName(key InstanceKey) string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if inst, ok := e.instances[key]; ok {
+		return inst.ProcessName
+	}
+	return ""
+}
+
+// retryDelay returns the backoff before the given attempt, doubling from base up to maxRetryDelay
+func retryDelay(base time.Duration, attempt int) time.Duration {
+	delay := base
+	for i := 1; i < attempt && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
+
+// instancePIDs returns the PIDs of the given instances
+func instancePIDs(keys []InstanceKey) []uint32 {
+	pids := make([]uint32, 0, len(keys))
+	for _, key := range keys {
+		pids = append(pids, key.PID)
+	}
+	return pids
+}
+
+// instanceStatuses returns the tracked instances ordered by PID (caller must hold e.mu)
+func (e *Engine) instanceStatuses() []InstanceStatus {
+	statuses := make([]InstanceStatus, 0, len(e.instances))
+	for _, inst := range e.instances {
+		statuses = append(statuses, inst.InstanceStatus)
+	}
+	slices.SortFunc(statuses, func(a, b InstanceStatus) int {
+		if c := cmp.Compare(a.PID, b.PID); c != 0 {
+			return c
+		}
+		return a.Created.Compare(b.Created)
+	})
+	return statuses
+}
@@ -0,0 +1,188 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/chenwei791129/multiablo/internal/config"
+	"github.com/chenwei791129/multiablo/internal/i18n"
+	"github.com/chenwei791129/multiablo/internal/process"
+)
+
+var (
+	// ErrNotRunning is returned when an operation needs the monitoring loops to be running
+	ErrNotRunning = errors.New("monitoring is not running")
+
+	// ErrLaunchTimeout is returned when a launched D2R instance did not have
+	// its single-instance handle closed in time
+	ErrLaunchTimeout = errors.New("timed out waiting for the single-instance handle to be closed")
+
+	// ErrLaunchExited is returned when a launched D2R instance exited before
+	// its single-instance handle was closed
+	ErrLaunchExited = errors.New("the process exited before its single-instance handle was closed")
+
+	// ErrLaunchFailed is returned when the monitor gave up closing the
+	// single-instance handle of a launched D2R instance
+	ErrLaunchFailed = errors.New("the single-instance handle could not be closed")
+)
+
+// launchStateInterval is how often a launch checks the state of the instance it started
+const launchStateInterval = 100 * time.Millisecond
+
+// stopSignal returns the channel closed when the monitoring loops stop,
+// or ErrNotRunning when they are not running
+func (e *Engine) stopSignal() (<-chan struct{}, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.running {
+		return nil, ErrNotRunning
+	}
+	return e.stopChan, nil
+}
+
+// startProfile starts D2R from a launch profile and reports the outcome as an event.
+// In observe-only mode it only reports the launch and returns ErrDryRun.
+func (e *Engine) startProfile(profile config.LaunchProfile) (uint32, error) {
+	if e.DryRun() {
+		e.emit(Event{
+			Type:        EventProcessLaunched,
+			ProcessName: exeName(profile.Path),
+			Path:        profile.Path,
+			DryRun:      true,
+			Message:     fmt.Sprintf(i18n.Get("[dry run] Would launch %s from %s"), profile.Label(), profile.Path),
+		})
+		return 0, ErrDryRun
+	}
+
+	pid, err := e.processes.LaunchProcess(profile.Path, process.LaunchOptions{
+		Args: profile.Args,
+		Dir:  profile.Dir(),
+		Env:  profile.Env,
+	})
+	if err != nil {
+		e.emit(Event{
+			Type:        EventError,
+			ProcessName: exeName(profile.Path),
+			Path:        profile.Path,
+			Err:         err,
+			Message:     fmt.Sprintf(i18n.Get("Failed to launch %s: %v"), profile.Label(), err),
+		})
+		return 0, err
+	}
+
+	e.emit(Event{
+		Type:        EventProcessLaunched,
+		ProcessName: exeName(profile.Path),
+		PID:         pid,
+		Path:        profile.Path,
+		Message:     fmt.Sprintf(i18n.Get("Launched %s (PID: %d)"), profile.Label(), pid),
+	})
+	return pid, nil
+}
+
+// waitHandleClosed waits until the instance started as pid is verified to hold
+// no single-instance handle, either because it was closed or because the
+// instance never created one. It gives up when the instance fails or exits,
+// the launch timeout expires, ctx is done or the monitoring loops stop.
+func (e *Engine) waitHandleClosed(ctx context.Context, stop <-chan struct{}, profile config.LaunchProfile, pid uint32) error {
+	cfg := e.settings()
+
+	// The creation time tells the launched instance apart from a later
+	// process given the same PID; zero matches the latest one
+	created, _ := e.processes.GetProcessCreationTime(pid)
+	key := InstanceKey{PID: pid, Created: created}
+
+	timeout := time.NewTimer(cfg.LaunchTimeout.Std())
+	defer timeout.Stop()
+
+	states := time.NewTicker(launchStateInterval)
+	defer states.Stop()
+
+	// Check regularly whether a process the watcher has not reported is still alive
+	alive := time.NewTicker(cfg.HandleCheckInterval.Std())
+	defer alive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-stop:
+			return ErrNotRunning
+		case <-timeout.C:
+			return e.launchError(profile, pid, fmt.Errorf("%w (PID: %d)", ErrLaunchTimeout, pid),
+				fmt.Sprintf(i18n.Get("%s (PID: %d) did not release its single-instance handle in time"), profile.Label(), pid))
+		case <-alive.C:
+			if _, tracked := e.launchedInstance(key); tracked || e.isGameRunning(pid) {
+				continue
+			}
+			return e.launchExited(profile, pid)
+		case <-states.C:
+			inst, tracked := e.launchedInstance(key)
+			if !tracked {
+				continue
+			}
+			switch inst.State {
+			case InstanceVerified:
+				return nil
+			case InstanceExited:
+				return e.launchExited(profile, pid)
+			case InstanceFailed:
+				return e.launchError(profile, pid, fmt.Errorf("%w (PID: %d): %v", ErrLaunchFailed, pid, inst.Err),
+					fmt.Sprintf(i18n.Get("%s (PID: %d) kept its single-instance handle: %v"), profile.Label(), pid, inst.Err))
+			}
+		}
+	}
+}
+
+// launchedInstance returns the status of the tracked instance with the given
+// key. A zero creation time matches the latest instance with the PID.
+func (e *Engine) launchedInstance(key InstanceKey) (InstanceStatus, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if inst, ok := e.instances[key]; ok {
+		return inst.InstanceStatus, true
+	}
+	if !key.Created.IsZero() {
+		return InstanceStatus{}, false
+	}
+
+	var latest *instance
+	for k, inst := range e.instances {
+		if k.PID == key.PID && (latest == nil || inst.detected.After(latest.detected)) {
+			latest = inst
+		}
+	}
+	if latest == nil {
+		return InstanceStatus{}, false
+	}
+	return latest.InstanceStatus, true
+}
+
+// launchExited reports that a launched instance exited before it was verified
+func (e *Engine) launchExited(profile config.LaunchProfile, pid uint32) error {
+	return e.launchError(profile, pid, fmt.Errorf("%w (PID: %d)", ErrLaunchExited, pid),
+		fmt.Sprintf(i18n.Get("%s (PID: %d) exited before its single-instance handle was closed"), profile.Label(), pid))
+}
+
+// launchError reports a failed wait for a launched instance as an event and returns err
+func (e *Engine) launchError(profile config.LaunchProfile, pid uint32, err error, message string) error {
+	e.emit(Event{
+		Type:        EventError,
+		ProcessName: exeName(profile.Path),
+		PID:         pid,
+		Err:         err,
+		Message:     message,
+	})
+	return err
+}
+
+// isGameRunning reports whether a process of an enabled game with the given
+// PID is running. A failed lookup counts as running so that waiting
+// continues until the timeout.
+func (e *Engine) isGameRunning(pid uint32) bool {
+	_, err := e.findGameProcess(pid)
+	return err == nil || !errors.Is(err, process.ErrNoProcess)
+}
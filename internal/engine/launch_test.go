@@ -0,0 +1,135 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/chenwei791129/multiablo/internal/config"
+	"github.com/chenwei791129/multiablo/internal/game"
+	"github.com/chenwei791129/multiablo/internal/handle"
+	"github.com/chenwei791129/multiablo/internal/process"
+	"github.com/chenwei791129/multiablo/pkg/d2r"
+)
+
+func TestStartProfileOptions(t *testing.T) {
+	f := process.NewFake(time.Now())
+	e := New(Options{Processes: f, Handles: handle.NewFake()})
+
+	profile := config.LaunchProfile{
+		Name: "main",
+		Path: `C:\Games\D2R\D2R.exe`,
+		Args: []string{"-mod", "mymod"},
+		Env:  []string{"KEY=value"},
+	}
+	if _, err := e.startProfile(profile); err != nil {
+		t.Fatalf("startProfile() error = %v", err)
+	}
+
+	launches := f.Launches()
+	if len(launches) != 1 {
+		t.Fatalf("Launches() = %v, want one launch", launches)
+	}
+	opts := launches[0].Options
+	if !slices.Equal(opts.Args, profile.Args) || opts.Dir != profile.Dir() || !slices.Equal(opts.Env, profile.Env) {
+		t.Errorf("launched with %+v, want the arguments, folder and environment of the profile", opts)
+	}
+}
+
+func TestWaitHandleClosed(t *testing.T) {
+	tests := []struct {
+		name string
+		// update changes the launched instance while the launch waits for it;
+		// untrack leaves the instance unreported by the watcher
+		update  func(e *Engine, f *process.Fake, key InstanceKey)
+		untrack bool
+		want    error
+	}{
+		{
+			name: "verified",
+			update: func(e *Engine, _ *process.Fake, key InstanceKey) {
+				e.setInstanceState(key, InstanceHandleClosed, nil)
+				e.setInstanceState(key, InstanceVerified, nil)
+			},
+		},
+		{
+			name: "failed",
+			update: func(e *Engine, _ *process.Fake, key InstanceKey) {
+				e.setInstanceState(key, InstanceFailed, errors.New("access denied"))
+			},
+			want: ErrLaunchFailed,
+		},
+		{
+			name: "exited",
+			update: func(e *Engine, f *process.Fake, key InstanceKey) {
+				f.RemoveProcess(key.PID)
+				e.instanceExited(key.PID)
+			},
+			want: ErrLaunchExited,
+		},
+		{
+			name:    "exited before it was reported",
+			untrack: true,
+			update: func(_ *Engine, f *process.Fake, key InstanceKey) {
+				f.RemoveProcess(key.PID)
+			},
+			want: ErrLaunchExited,
+		},
+		{
+			name: "dry-run handle closed event",
+			update: func(e *Engine, _ *process.Fake, key InstanceKey) {
+				e.setInstanceState(key, InstanceObserved, nil)
+				e.emit(Event{Type: EventHandleClosed, PID: key.PID, DryRun: true})
+			},
+			want: ErrLaunchTimeout,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := process.NewFake(time.Now())
+			cfg := config.Default()
+			cfg.LaunchTimeout = config.Duration(time.Second)
+			cfg.HandleCheckInterval = config.Duration(20 * time.Millisecond)
+			e := New(Options{Config: &cfg, Processes: f, Handles: handle.NewFake()})
+
+			profile := config.LaunchProfile{Name: "main", Path: `C:\Games\D2R\` + d2r.ProcessName}
+			pid, err := e.startProfile(profile)
+			if err != nil {
+				t.Fatalf("startProfile() error = %v", err)
+			}
+			created, err := f.GetProcessCreationTime(pid)
+			if err != nil {
+				t.Fatalf("GetProcessCreationTime() error = %v", err)
+			}
+			key := InstanceKey{PID: pid, Created: created}
+			if !tt.untrack {
+				e.trackInstance(process.ProcessInfo{PID: pid, Name: d2r.ProcessName, CreationTime: created}, game.D2RID)
+			}
+
+			done := make(chan error, 1)
+			go func() {
+				done <- e.waitHandleClosed(context.Background(), make(chan struct{}), profile, pid)
+			}()
+
+			time.Sleep(2 * launchStateInterval)
+			select {
+			case err := <-done:
+				t.Fatalf("waitHandleClosed() = %v before the instance changed", err)
+			default:
+			}
+			tt.update(e, f, key)
+
+			select {
+			case err := <-done:
+				if tt.want == nil && err != nil || !errors.Is(err, tt.want) {
+					t.Errorf("waitHandleClosed() error = %v, want %v", err, tt.want)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("waitHandleClosed() did not return")
+			}
+		})
+	}
+}
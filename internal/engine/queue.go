@@ -0,0 +1,213 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/chenwei791129/multiablo/internal/config"
+	"github.com/chenwei791129/multiablo/internal/i18n"
+)
+
+const (
+	// launchRetryDelay is the pause before a failed launch is attempted again
+	launchRetryDelay = 2 * time.Second
+)
+
+// LaunchState is the progress of one entry of a launch queue
+type LaunchState int
+
+const (
+	// LaunchPending means the entry has not been started yet
+	LaunchPending LaunchState = iota
+	// LaunchStarting means the entry's process is being started
+	LaunchStarting
+	// LaunchWaiting means the queue waits for the new instance's handle to be closed
+	LaunchWaiting
+	// LaunchRetrying means an attempt failed and another one follows
+	LaunchRetrying
+	// LaunchReady means the handle was closed and the next entry can start
+	LaunchReady
+	// LaunchFailed means every attempt failed
+	LaunchFailed
+	// LaunchCancelled means the queue stopped before the entry finished
+	LaunchCancelled
+)
+
+// String returns the name of the launch state
+func (s LaunchState) String() string {
+	switch s {
+	case LaunchPending:
+		return "pending"
+	case LaunchStarting:
+		return "starting"
+	case LaunchWaiting:
+		return "waiting"
+	case LaunchRetrying:
+		return "retrying"
+	case LaunchReady:
+		return "ready"
+	case LaunchFailed:
+		return "failed"
+	case LaunchCancelled:
+		return "cancelled"
+	default:
+		return "unknown"
+	}
+}
+
+// LaunchProgress reports a state change of one launch queue entry
+type LaunchProgress struct {
+	// Index is the position of the entry in the queue
+	Index   int
+	Profile config.LaunchProfile
+	State   LaunchState
+
+	// Attempt counts from 1; MaxAttempts includes the retries
+	Attempt     int
+	MaxAttempts int
+
+	// PID is the process started for the entry, if any
+	PID uint32
+
+	// Err is the reason of the last failure, if any
+	Err error
+}
+
+// LaunchQueue starts the given profiles one at a time. Before each next
+// start it waits until the monitoring loop has closed the single-instance
+// handle of the previous instance. A failed attempt is retried up to the
+// configured number of times: an instance that is still running is waited
+// for again, otherwise the profile is launched again. An entry that fails
+// for good does not stop the queue.
+//
+// Every state change is passed to progress, which may be nil. The engine
+// must be running. An error is returned when the queue was cancelled or
+// when at least one entry failed. In observe-only mode every entry is only
+// reported and marked cancelled, and ErrDryRun is returned.
+func (e *Engine) LaunchQueue(ctx context.Context, profiles []config.LaunchProfile, progress func(LaunchProgress)) error {
+	if progress == nil {
+		progress = func(LaunchProgress) {}
+	}
+
+	stop, err := e.stopSignal()
+	if err != nil {
+		return err
+	}
+	defer e.beginLaunch()()
+
+	maxAttempts := e.settings().LaunchRetries + 1
+	for i, profile := range profiles {
+		progress(LaunchProgress{Index: i, Profile: profile, State: LaunchPending, MaxAttempts: maxAttempts})
+	}
+
+	failed := 0
+	dryRun := false
+	for i, profile := range profiles {
+		err := e.launchEntry(ctx, stop, i, profile, maxAttempts, progress)
+		if err == nil {
+			continue
+		}
+
+		if errors.Is(err, ErrDryRun) {
+			dryRun = true
+			progress(LaunchProgress{Index: i, Profile: profile, State: LaunchCancelled, Attempt: 1, MaxAttempts: maxAttempts, Err: err})
+			continue
+		}
+
+		if isCancellation(err) {
+			for j := i; j < len(profiles); j++ {
+				progress(LaunchProgress{Index: j, Profile: profiles[j], State: LaunchCancelled, MaxAttempts: maxAttempts, Err: err})
+			}
+			return err
+		}
+		failed++
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d launches failed", failed, len(profiles))
+	}
+	if dryRun {
+		return ErrDryRun
+	}
+	return nil
+}
+
+// launchEntry launches one queue entry with retries and reports its progress
+func (e *Engine) launchEntry(ctx context.Context, stop <-chan struct{}, index int, profile config.LaunchProfile, maxAttempts int, progress func(LaunchProgress)) error {
+	var pid uint32
+	var err error
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		report := func(state LaunchState, err error) {
+			progress(LaunchProgress{
+				Index:       index,
+				Profile:     profile,
+				State:       state,
+				Attempt:     attempt,
+				MaxAttempts: maxAttempts,
+				PID:         pid,
+				Err:         err,
+			})
+		}
+
+		if attempt > 1 {
+			report(LaunchRetrying, err)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-stop:
+				return ErrNotRunning
+			case <-time.After(launchRetryDelay):
+			}
+		}
+
+		// Launch again unless the instance of the previous attempt is still starting up
+		if pid == 0 || !e.isGameRunning(pid) {
+			pid = 0
+			report(LaunchStarting, nil)
+			pid, err = e.startProfile(profile)
+			if errors.Is(err, ErrDryRun) {
+				return err
+			}
+			if err != nil {
+				continue
+			}
+		}
+
+		report(LaunchWaiting, nil)
+		err = e.waitHandleClosed(ctx, stop, profile, pid)
+		if err == nil {
+			report(LaunchReady, nil)
+			return nil
+		}
+		if isCancellation(err) {
+			return err
+		}
+	}
+
+	progress(LaunchProgress{
+		Index:       index,
+		Profile:     profile,
+		State:       LaunchFailed,
+		Attempt:     maxAttempts,
+		MaxAttempts: maxAttempts,
+		PID:         pid,
+		Err:         err,
+	})
+	e.emit(Event{
+		Type:        EventError,
+		ProcessName: exeName(profile.Path),
+		PID:         pid,
+		Path:        profile.Path,
+		Err:         err,
+		Message:     fmt.Sprintf(i18n.Get("Gave up launching %s after %d attempt(s): %v"), profile.Label(), maxAttempts, err),
+	})
+	return err
+}
+
+// isCancellation reports whether err means the queue was stopped rather than a launch failing
+func isCancellation(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrNotRunning)
+}
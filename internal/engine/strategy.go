@@ -0,0 +1,180 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/chenwei791129/multiablo/internal/config"
+)
+
+// AgentAction is what happens to a running launcher helper such as Agent.exe
+type AgentAction int
+
+const (
+	// AgentKeep leaves the helper running
+	AgentKeep AgentAction = iota
+	// AgentKill terminates the helper
+	AgentKill
+	// AgentKillRelaunch terminates the helper and starts it again with the
+	// path and arguments it ran with
+	AgentKillRelaunch
+)
+
+// String returns the name of the action
+func (a AgentAction) String() string {
+	switch a {
+	case AgentKeep:
+		return "keep"
+	case AgentKill:
+		return "kill"
+	case AgentKillRelaunch:
+		return "kill_relaunch"
+	default:
+		return "unknown"
+	}
+}
+
+// AgentState is what an AgentStrategy decides on
+type AgentState struct {
+	// Helpers are the running launcher helper processes with their uptime
+	Helpers []ProcessStatus
+
+	// GameInstances is the number of running game instances
+	GameInstances int
+
+	// Launching is set while the engine launches a game from a launch profile
+	Launching bool
+}
+
+// AgentStrategy decides what happens to each launcher helper. It is asked
+// for every running helper every agent_check_interval.
+type AgentStrategy interface {
+	Decide(state AgentState, helper ProcessStatus) AgentAction
+}
+
+// AgentStrategyFunc adapts a function to the AgentStrategy interface
+type AgentStrategyFunc func(state AgentState, helper ProcessStatus) AgentAction
+
+// Decide calls f
+func (f AgentStrategyFunc) Decide(state AgentState, helper ProcessStatus) AgentAction {
+	return f(state, helper)
+}
+
+// NewAgentStrategy returns the built-in strategy selected by the
+// agent_strategy setting of cfg
+func NewAgentStrategy(cfg config.Config) (AgentStrategy, error) {
+	threshold := cfg.AgentKillThreshold.Std()
+	switch cfg.AgentStrategy {
+	case config.StrategyKillRelaunch:
+		return KillRelaunchStrategy(threshold), nil
+	case config.StrategyKillOnly:
+		return KillOnlyStrategy(threshold), nil
+	case config.StrategyLeaveAlone:
+		return LeaveAloneStrategy(), nil
+	case config.StrategyDuringLaunch:
+		return DuringLaunchStrategy(threshold), nil
+	case config.StrategyMinInstances:
+		return MinInstancesStrategy(threshold, cfg.AgentMinInstances), nil
+	default:
+		return nil, fmt.Errorf("unknown agent strategy %q", cfg.AgentStrategy)
+	}
+}
+
+// KillRelaunchStrategy terminates each helper once it has run for
+// threshold, then relaunches it
+func KillRelaunchStrategy(threshold time.Duration) AgentStrategy {
+	return AgentStrategyFunc(func(_ AgentState, helper ProcessStatus) AgentAction {
+		if helper.Uptime < threshold {
+			return AgentKeep
+		}
+		return AgentKillRelaunch
+	})
+}
+
+// KillOnlyStrategy terminates each helper once it has run for threshold,
+// without relaunching it
+func KillOnlyStrategy(threshold time.Duration) AgentStrategy {
+	return AgentStrategyFunc(func(_ AgentState, helper ProcessStatus) AgentAction {
+		if helper.Uptime < threshold {
+			return AgentKeep
+		}
+		return AgentKill
+	})
+}
+
+// LeaveAloneStrategy never terminates the helpers
+func LeaveAloneStrategy() AgentStrategy {
+	return AgentStrategyFunc(func(AgentState, ProcessStatus) AgentAction {
+		return AgentKeep
+	})
+}
+
+// DuringLaunchStrategy behaves like KillRelaunchStrategy while a game is
+// launched from a launch profile, and leaves the helpers alone otherwise
+func DuringLaunchStrategy(threshold time.Duration) AgentStrategy {
+	return whenStrategy(func(state AgentState) bool {
+		return state.Launching
+	}, KillRelaunchStrategy(threshold))
+}
+
+// MinInstancesStrategy behaves like KillRelaunchStrategy while at least
+// minInstances game instances run, and leaves the helpers alone otherwise
+func MinInstancesStrategy(threshold time.Duration, minInstances int) AgentStrategy {
+	return whenStrategy(func(state AgentState) bool {
+		return state.GameInstances >= minInstances
+	}, KillRelaunchStrategy(threshold))
+}
+
+// whenStrategy asks strategy only when cond holds, and keeps the helpers otherwise
+func whenStrategy(cond func(AgentState) bool, strategy AgentStrategy) AgentStrategy {
+	return AgentStrategyFunc(func(state AgentState, helper ProcessStatus) AgentAction {
+		if !cond(state) {
+			return AgentKeep
+		}
+		return strategy.Decide(state, helper)
+	})
+}
+
+// agentStrategy returns the strategy passed in the options, or the one
+// selected by the current settings. Invalid settings fall back to the default.
+func (e *Engine) agentStrategy() AgentStrategy {
+	if e.customStrategy != nil {
+		return e.customStrategy
+	}
+	strategy, err := NewAgentStrategy(e.settings())
+	if err != nil {
+		strategy, _ = NewAgentStrategy(config.Default())
+	}
+	return strategy
+}
+
+// agentState collects what the strategy decides on
+func (e *Engine) agentState() AgentState {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	state := AgentState{
+		Helpers:   sortedStatuses(e.agentProcesses),
+		Launching: e.launching > 0,
+	}
+	for _, inst := range e.instances {
+		if inst.State != InstanceExited {
+			state.GameInstances++
+		}
+	}
+	return state
+}
+
+// beginLaunch marks a launch from a launch profile as in progress until
+// the returned function is called
+func (e *Engine) beginLaunch() func() {
+	e.mu.Lock()
+	e.launching++
+	e.mu.Unlock()
+
+	return func() {
+		e.mu.Lock()
+		e.launching--
+		e.mu.Unlock()
+	}
+}
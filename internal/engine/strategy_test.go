@@ -0,0 +1,272 @@
+package engine
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/chenwei791129/multiablo/internal/config"
+	"github.com/chenwei791129/multiablo/internal/handle"
+	"github.com/chenwei791129/multiablo/internal/process"
+	"github.com/chenwei791129/multiablo/pkg/d2r"
+)
+
+const testAgentPath = `C:\ProgramData\Battle.net\Agent\Agent.exe`
+
+func TestAgentStrategies(t *testing.T) {
+	const threshold = 7 * time.Second
+	young := ProcessStatus{PID: 1, Uptime: threshold - time.Second}
+	old := ProcessStatus{PID: 2, Uptime: threshold}
+
+	tests := []struct {
+		name     string
+		strategy string
+		state    AgentState
+		young    AgentAction
+		old      AgentAction
+	}{
+		{name: "kill and relaunch", strategy: config.StrategyKillRelaunch, young: AgentKeep, old: AgentKillRelaunch},
+		{name: "kill only", strategy: config.StrategyKillOnly, young: AgentKeep, old: AgentKill},
+		{name: "leave alone", strategy: config.StrategyLeaveAlone, young: AgentKeep, old: AgentKeep},
+		{name: "not launching", strategy: config.StrategyDuringLaunch, young: AgentKeep, old: AgentKeep},
+		{name: "launching", strategy: config.StrategyDuringLaunch, state: AgentState{Launching: true}, young: AgentKeep, old: AgentKillRelaunch},
+		{name: "too few instances", strategy: config.StrategyMinInstances, state: AgentState{GameInstances: 1}, young: AgentKeep, old: AgentKeep},
+		{name: "enough instances", strategy: config.StrategyMinInstances, state: AgentState{GameInstances: 2}, young: AgentKeep, old: AgentKillRelaunch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.AgentStrategy = tt.strategy
+			cfg.AgentKillThreshold = config.Duration(threshold)
+			cfg.AgentMinInstances = 2
+
+			strategy, err := NewAgentStrategy(cfg)
+			if err != nil {
+				t.Fatalf("NewAgentStrategy() error = %v", err)
+			}
+			if got := strategy.Decide(tt.state, young); got != tt.young {
+				t.Errorf("Decide(young helper) = %v, want %v", got, tt.young)
+			}
+			if got := strategy.Decide(tt.state, old); got != tt.old {
+				t.Errorf("Decide(old helper) = %v, want %v", got, tt.old)
+			}
+		})
+	}
+}
+
+func TestNewAgentStrategyUnknown(t *testing.T) {
+	cfg := config.Default()
+	cfg.AgentStrategy = "suspend"
+	if _, err := NewAgentStrategy(cfg); err == nil {
+		t.Error("NewAgentStrategy() error = nil, want an error for an unknown strategy")
+	}
+}
+
+// newAgentTestEngine returns an engine with the given agent strategy that
+// tracks one Agent.exe started uptime ago on a fake process backend
+func newAgentTestEngine(t *testing.T, strategy string, dryRun bool, uptime time.Duration) (*Engine, *process.Fake, uint32) {
+	t.Helper()
+
+	now := time.Now()
+	f := process.NewFake(now)
+	pid := f.AddProcess(process.FakeProcess{
+		Name:      d2r.AgentProcessName,
+		Path:      testAgentPath,
+		Args:      []string{"--uid", "battle net"},
+		StartTime: now.Add(-uptime),
+	})
+
+	cfg := config.Default()
+	cfg.AgentStrategy = strategy
+	e := New(Options{Config: &cfg, Processes: f, Handles: handle.NewFake(), DryRun: dryRun})
+
+	processes, err := f.FindProcessesByName(d2r.AgentProcessName)
+	if err != nil || len(processes) != 1 {
+		t.Fatalf("FindProcessesByName() = %v, %v", processes, err)
+	}
+	e.processStarted(f.ReadProcessDetails(processes[0]))
+	return e, f, pid
+}
+
+// drainEvents returns the events delivered to a subscription so far
+func drainEvents(events <-chan Event) []Event {
+	var got []Event
+	for {
+		select {
+		case event := <-events:
+			got = append(got, event)
+		default:
+			return got
+		}
+	}
+}
+
+func TestCheckAgentProcessesKillRelaunch(t *testing.T) {
+	e, f, pid := newAgentTestEngine(t, config.StrategyKillRelaunch, false, 10*time.Second)
+
+	e.checkAgentProcesses()
+
+	if got := f.Killed(); !slices.Equal(got, []uint32{pid}) {
+		t.Fatalf("Killed() = %v, want [%d]", got, pid)
+	}
+	launches := f.Launches()
+	if len(launches) != 1 {
+		t.Fatalf("Launches() = %v, want one relaunch", launches)
+	}
+	if launches[0].Path != testAgentPath || !slices.Equal(launches[0].Options.Args, []string{"--uid", "battle net"}) {
+		t.Errorf("relaunched %q with %q, want %q with the original arguments",
+			launches[0].Path, launches[0].Options.Args, testAgentPath)
+	}
+	if got := e.Status().AgentsKilled; got != 1 {
+		t.Errorf("Status().AgentsKilled = %d, want 1", got)
+	}
+}
+
+func TestCheckAgentProcessesKillOnly(t *testing.T) {
+	e, f, pid := newAgentTestEngine(t, config.StrategyKillOnly, false, 10*time.Second)
+
+	e.checkAgentProcesses()
+
+	if got := f.Killed(); !slices.Equal(got, []uint32{pid}) {
+		t.Errorf("Killed() = %v, want [%d]", got, pid)
+	}
+	if got := f.Launches(); len(got) != 0 {
+		t.Errorf("Launches() = %v, want none", got)
+	}
+}
+
+func TestCheckAgentProcessesKeep(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy string
+		uptime   time.Duration
+	}{
+		{name: "leave alone", strategy: config.StrategyLeaveAlone, uptime: time.Hour},
+		{name: "below threshold", strategy: config.StrategyKillRelaunch, uptime: time.Second},
+		{name: "not launching", strategy: config.StrategyDuringLaunch, uptime: time.Hour},
+		{name: "too few instances", strategy: config.StrategyMinInstances, uptime: time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, f, _ := newAgentTestEngine(t, tt.strategy, false, tt.uptime)
+
+			e.checkAgentProcesses()
+
+			if got := f.Killed(); len(got) != 0 {
+				t.Errorf("Killed() = %v, want none", got)
+			}
+			if got := f.Launches(); len(got) != 0 {
+				t.Errorf("Launches() = %v, want none", got)
+			}
+		})
+	}
+}
+
+func TestCheckAgentProcessesDryRun(t *testing.T) {
+	e, f, pid := newAgentTestEngine(t, config.StrategyKillRelaunch, true, 10*time.Second)
+	events, unsubscribe := e.Subscribe()
+	defer unsubscribe()
+
+	e.checkAgentProcesses()
+
+	if got := f.Killed(); len(got) != 0 {
+		t.Errorf("Killed() = %v, want none in dry-run mode", got)
+	}
+	if got := f.Launches(); len(got) != 0 {
+		t.Errorf("Launches() = %v, want none in dry-run mode", got)
+	}
+
+	var killed, relaunched int
+	for _, event := range drainEvents(events) {
+		switch event.Type {
+		case EventAgentKilled:
+			killed++
+			if !event.DryRun || event.PID != pid {
+				t.Errorf("kill event = %+v, want a dry-run event for PID %d", event, pid)
+			}
+		case EventAgentRelaunched:
+			relaunched++
+			if !event.DryRun || event.Path != testAgentPath {
+				t.Errorf("relaunch event = %+v, want a dry-run event for %s", event, testAgentPath)
+			}
+		}
+	}
+	if killed != 1 || relaunched != 1 {
+		t.Errorf("got %d kill and %d relaunch events, want one of each", killed, relaunched)
+	}
+
+	// A helper already reported is not reported again
+	e.checkAgentProcesses()
+	if got := drainEvents(events); len(got) != 0 {
+		t.Errorf("second check emitted %d events, want none", len(got))
+	}
+	if got := e.Status().AgentsKilled; got != 0 {
+		t.Errorf("Status().AgentsKilled = %d, want 0 in dry-run mode", got)
+	}
+}
+
+func TestCheckAgentProcessesReusedPID(t *testing.T) {
+	e, f, pid := newAgentTestEngine(t, config.StrategyKillRelaunch, false, 10*time.Second)
+
+	// The helper exits and another Agent.exe gets its PID
+	f.RemoveProcess(pid)
+	f.AddProcess(process.FakeProcess{PID: pid, Name: d2r.AgentProcessName, StartTime: f.Now()})
+	f.Advance(10 * time.Second)
+
+	e.checkAgentProcesses()
+
+	if got := f.Killed(); len(got) != 0 {
+		t.Errorf("Killed() = %v, want the process that reused the PID left running", got)
+	}
+	if got := f.Launches(); len(got) != 0 {
+		t.Errorf("Launches() = %v, want no relaunch after a failed kill", got)
+	}
+}
+
+func TestCheckAgentProcessesPerProcess(t *testing.T) {
+	const otherPath = `D:\Battle.net\Agent\Agent.exe`
+	e, f, old := newAgentTestEngine(t, config.StrategyKillRelaunch, false, 10*time.Second)
+
+	// A second helper past the threshold with its own path and arguments,
+	// and a young one that is kept until it reaches the threshold
+	other := f.AddProcess(process.FakeProcess{
+		Name:      d2r.AgentProcessName,
+		Path:      otherPath,
+		Args:      []string{"--other"},
+		StartTime: f.Now().Add(-time.Minute),
+	})
+	young := f.AddProcess(process.FakeProcess{Name: d2r.AgentProcessName, Path: testAgentPath, StartTime: f.Now()})
+	for _, pid := range []uint32{other, young} {
+		e.processStarted(f.ReadProcessDetails(process.ProcessInfo{PID: pid, Name: d2r.AgentProcessName}))
+	}
+
+	e.checkAgentProcesses()
+
+	killed := f.Killed()
+	slices.Sort(killed)
+	if want := []uint32{old, other}; !slices.Equal(killed, want) {
+		t.Fatalf("Killed() = %v, want only the helpers past the threshold %v", killed, want)
+	}
+	relaunched := make(map[string][]string)
+	for _, launch := range f.Launches() {
+		relaunched[launch.Path] = launch.Options.Args
+	}
+	if len(relaunched) != 2 || !slices.Equal(relaunched[testAgentPath], []string{"--uid", "battle net"}) ||
+		!slices.Equal(relaunched[otherPath], []string{"--other"}) {
+		t.Errorf("relaunched %v, want each helper with its own path and arguments", relaunched)
+	}
+	for _, pid := range []uint32{old, other} {
+		if r := relaunchOf(t, e, pid); r.pid == 0 {
+			t.Errorf("the relaunch of PID %d does not follow the new process", pid)
+		}
+	}
+
+	// The young helper is terminated once it reaches the threshold itself
+	f.Advance(10 * time.Second)
+	e.checkAgentProcesses()
+	if killed := f.Killed(); !slices.Contains(killed, young) {
+		t.Errorf("Killed() = %v, want PID %d once it is past the threshold", killed, young)
+	}
+}
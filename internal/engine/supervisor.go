@@ -0,0 +1,268 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/chenwei791129/multiablo/internal/i18n"
+)
+
+const (
+	// relaunchStartTimeout is how long a relaunched helper has to appear in the process list
+	relaunchStartTimeout = 10 * time.Second
+
+	// relaunchBaseDelay is the first delay before a failed relaunch is tried again
+	relaunchBaseDelay = 2 * time.Second
+
+	// maxRelaunchFailures is how many relaunches of a helper may fail in a
+	// row before the agent strategy is paused
+	maxRelaunchFailures = 5
+)
+
+// relaunch is a launcher helper relaunched by the engine. It is watched
+// until it has kept running for agent_crash_window, and tried again with
+// exponential backoff when it does not start or crashes.
+type relaunch struct {
+	name string
+	path string
+	args []string
+
+	// killedAt is when the original helper was terminated
+	killedAt time.Time
+
+	// failures counts the failed relaunches in a row
+	failures int
+
+	// pid is the relaunched process; launchedAt is when it was started,
+	// zero while a retry is pending
+	pid        uint32
+	launchedAt time.Time
+
+	// seen is set once the watcher reported the relaunched process
+	seen bool
+
+	// retryAt is when the next attempt is due while launchedAt is zero
+	retryAt time.Time
+}
+
+// superviseRelaunch relaunches a terminated launcher helper with the path
+// and arguments it ran with, and watches the new process until it has
+// proven to run
+func (e *Engine) superviseRelaunch(helper ProcessStatus) {
+	key := helper.PID
+
+	e.mu.Lock()
+	e.relaunches[key] = &relaunch{
+		name:     helper.Name,
+		path:     helper.Path,
+		args:     helper.Args,
+		killedAt: time.Now(),
+	}
+	e.mu.Unlock()
+
+	e.startRelaunch(key)
+}
+
+// startRelaunch starts the helper of a relaunch record
+func (e *Engine) startRelaunch(key uint32) {
+	e.mu.Lock()
+	r, ok := e.relaunches[key]
+	if !ok {
+		e.mu.Unlock()
+		return
+	}
+	path, args := r.path, r.args
+	r.pid = 0
+	r.launchedAt = time.Now()
+	r.seen = false
+	e.mu.Unlock()
+
+	pid, err := e.RelaunchAgent(path, args...)
+	switch {
+	case errors.Is(err, ErrDryRun):
+		e.mu.Lock()
+		delete(e.relaunches, key)
+		e.mu.Unlock()
+	case err != nil:
+		e.relaunchFailed(key, err)
+	default:
+		e.mu.Lock()
+		if r, ok := e.relaunches[key]; ok {
+			r.pid = pid
+			// The watcher may have reported the process before its PID was known
+			_, r.seen = e.agentProcesses[pid]
+		}
+		e.mu.Unlock()
+	}
+}
+
+// relaunchSeen marks the relaunch whose process the watcher reported as started
+func (e *Engine) relaunchSeen(pid uint32) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, r := range e.relaunches {
+		if r.pid == pid && !r.launchedAt.IsZero() {
+			r.seen = true
+		}
+	}
+}
+
+// checkRelaunches confirms relaunched helpers that kept running, fails
+// those that did not start or crashed, and retries failed ones when due
+func (e *Engine) checkRelaunches() {
+	crashWindow := e.settings().AgentCrashWindow.Std()
+	now := time.Now()
+
+	e.mu.Lock()
+	var failed map[uint32]error
+	var retry []uint32
+	for key, r := range e.relaunches {
+		if r.launchedAt.IsZero() {
+			if now.Before(r.retryAt) {
+				continue
+			}
+			if e.helperStartedSince(r.name, r.killedAt) {
+				// Started by someone else, e.g. the Battle.net launcher
+				delete(e.relaunches, key)
+				continue
+			}
+			retry = append(retry, key)
+			continue
+		}
+
+		age := now.Sub(r.launchedAt)
+		_, running := e.agentProcesses[r.pid]
+		var err error
+		switch {
+		case running && age >= crashWindow:
+			delete(e.relaunches, key)
+		case running:
+		case r.seen:
+			err = fmt.Errorf("%s exited less than %s after it was relaunched from %s", r.name, crashWindow, r.path)
+		case age >= relaunchStartTimeout:
+			err = fmt.Errorf("%s did not start within %s after it was relaunched from %s", r.name, relaunchStartTimeout, r.path)
+		}
+		if err != nil {
+			if failed == nil {
+				failed = make(map[uint32]error)
+			}
+			failed[key] = err
+		}
+	}
+	e.mu.Unlock()
+
+	for key, err := range failed {
+		e.relaunchFailed(key, err)
+	}
+	for _, key := range retry {
+		if e.AgentsPaused() {
+			return
+		}
+		e.startRelaunch(key)
+	}
+}
+
+// settleRelaunches counts the relaunched helpers that are running as
+// started. It is called before the helpers are terminated on purpose, so
+// that their exit is not taken for a crash.
+func (e *Engine) settleRelaunches() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for key, r := range e.relaunches {
+		if _, running := e.agentProcesses[r.pid]; running && !r.launchedAt.IsZero() {
+			delete(e.relaunches, key)
+		}
+	}
+}
+
+// relaunchFailed schedules another attempt with exponential backoff, or
+// pauses the agent strategy once too many attempts failed in a row
+func (e *Engine) relaunchFailed(key uint32, err error) {
+	e.mu.Lock()
+	r, ok := e.relaunches[key]
+	if !ok {
+		e.mu.Unlock()
+		return
+	}
+	r.failures++
+	r.launchedAt = time.Time{}
+	name, failures := r.name, r.failures
+
+	if failures >= maxRelaunchFailures {
+		e.agentsPaused = true
+		e.agentPauseHelper = name
+		e.agentPauseErr = err
+		clear(e.relaunches)
+		e.mu.Unlock()
+
+		e.emit(Event{
+			Type:        EventAgentPaused,
+			ProcessName: name,
+			Count:       failures,
+			Err:         err,
+			Message: fmt.Sprintf(i18n.Get("Paused the handling of %[1]s after %[2]d failed relaunches: %[3]v. Check the path of %[1]s, then resume or restart monitoring."),
+				name, failures, err),
+		})
+		return
+	}
+
+	delay := relaunchDelay(failures)
+	r.retryAt = time.Now().Add(delay)
+	e.mu.Unlock()
+
+	e.emit(Event{
+		Type:        EventError,
+		ProcessName: name,
+		Count:       failures,
+		Err:         err,
+		Message:     fmt.Sprintf(i18n.Get("Relaunch of %s failed (%v), trying again in %s"), name, err, delay),
+	})
+}
+
+// relaunchDelay returns the backoff after the given number of failed relaunches
+func relaunchDelay(failures int) time.Duration {
+	return retryDelay(relaunchBaseDelay, failures)
+}
+
+// helperStartedSince reports whether a launcher helper with the given name
+// was started after t, e.g. by the Battle.net launcher (caller must hold e.mu)
+func (e *Engine) helperStartedSince(name string, t time.Time) bool {
+	for _, p := range e.agentProcesses {
+		if strings.EqualFold(p.Name, name) && p.StartTime.After(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// AgentsPaused reports whether the agent strategy was paused because
+// relaunched launcher helpers kept failing
+func (e *Engine) AgentsPaused() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.agentsPaused
+}
+
+// ResumeAgents resumes the agent strategy after it was paused, forgetting
+// the failed relaunches
+func (e *Engine) ResumeAgents() {
+	e.mu.Lock()
+	paused, name := e.agentsPaused, e.agentPauseHelper
+	e.agentsPaused = false
+	e.agentPauseHelper = ""
+	e.agentPauseErr = nil
+	clear(e.relaunches)
+	e.mu.Unlock()
+
+	if paused {
+		e.emit(Event{
+			Type:        EventAgentResumed,
+			ProcessName: name,
+			Message:     fmt.Sprintf(i18n.Get("Resumed the handling of %s"), name),
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package engine
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chenwei791129/multiablo/internal/config"
+	"github.com/chenwei791129/multiablo/internal/process"
+	"github.com/chenwei791129/multiablo/pkg/d2r"
+)
+
+// relaunchOf returns a copy of the relaunch record of the helper terminated with pid
+func relaunchOf(t *testing.T, e *Engine, pid uint32) relaunch {
+	t.Helper()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	r, ok := e.relaunches[pid]
+	if !ok {
+		t.Fatalf("no relaunch is supervised for PID %d", pid)
+	}
+	return *r
+}
+
+// updateRelaunch changes the relaunch record of the helper terminated with pid
+func updateRelaunch(e *Engine, pid uint32, update func(r *relaunch)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if r, ok := e.relaunches[pid]; ok {
+		update(r)
+	}
+}
+
+func TestRelaunchBackoffAndPause(t *testing.T) {
+	e, f, pid := newAgentTestEngine(t, config.StrategyKillRelaunch, false, 10*time.Second)
+	events, unsubscribe := e.Subscribe()
+	defer unsubscribe()
+	f.SetLaunchError(errors.New("access denied"))
+
+	e.checkAgentProcesses()
+
+	for failures := 1; failures < maxRelaunchFailures; failures++ {
+		r := relaunchOf(t, e, pid)
+		if r.failures != failures {
+			t.Fatalf("failures = %d, want %d", r.failures, failures)
+		}
+		delay := time.Until(r.retryAt)
+		if want := relaunchDelay(failures); delay <= want-time.Second || delay > want {
+			t.Errorf("retry after failure %d is due in %s, want %s", failures, delay, want)
+		}
+
+		// Nothing is retried before the backoff expires
+		e.checkRelaunches()
+		if got := len(f.Launches()); got != failures {
+			t.Fatalf("Launches() = %d before the backoff expired, want %d", got, failures)
+		}
+
+		updateRelaunch(e, pid, func(r *relaunch) {
+			r.retryAt = time.Now()
+		})
+		e.checkRelaunches()
+	}
+
+	status := e.Status()
+	if !status.AgentsPaused || status.AgentPauseHelper != d2r.AgentProcessName || status.AgentPauseErr == nil {
+		t.Fatalf("Status() paused = %v, helper = %q, err = %v, want paused by %s",
+			status.AgentsPaused, status.AgentPauseHelper, status.AgentPauseErr, d2r.AgentProcessName)
+	}
+	if got := len(f.Launches()); got != maxRelaunchFailures {
+		t.Errorf("Launches() = %d, want %d", got, maxRelaunchFailures)
+	}
+
+	var paused *Event
+	for _, event := range drainEvents(events) {
+		if event.Type == EventAgentPaused {
+			paused = &event
+		}
+	}
+	if paused == nil {
+		t.Fatal("no pause event was emitted")
+	}
+	if paused.ProcessName != d2r.AgentProcessName || paused.Count != maxRelaunchFailures ||
+		!strings.Contains(paused.Message, d2r.AgentProcessName) {
+		t.Errorf("pause event = %+v, want %d failures of %s", *paused, maxRelaunchFailures, d2r.AgentProcessName)
+	}
+
+	// A paused strategy neither terminates nor relaunches helpers
+	f.SetLaunchError(nil)
+	other := f.AddProcess(process.FakeProcess{Name: d2r.AgentProcessName, Path: testAgentPath, StartTime: f.Now().Add(-time.Minute)})
+	e.processStarted(f.ReadProcessDetails(process.ProcessInfo{PID: other, Name: d2r.AgentProcessName}))
+	e.checkAgentProcesses()
+	e.checkRelaunches()
+	if got := len(f.Killed()); got != 1 {
+		t.Errorf("Killed() = %d helpers while paused, want only the first one", got)
+	}
+	if got := len(f.Launches()); got != maxRelaunchFailures {
+		t.Errorf("Launches() = %d while paused, want %d", got, maxRelaunchFailures)
+	}
+
+	e.ResumeAgents()
+	if e.AgentsPaused() {
+		t.Error("AgentsPaused() = true after ResumeAgents()")
+	}
+	var resumed []Event
+	for _, event := range drainEvents(events) {
+		if event.Type == EventAgentResumed {
+			resumed = append(resumed, event)
+		}
+	}
+	if len(resumed) != 1 || resumed[0].ProcessName != d2r.AgentProcessName ||
+		!strings.Contains(resumed[0].Message, d2r.AgentProcessName) {
+		t.Errorf("resume events = %+v, want one naming %s", resumed, d2r.AgentProcessName)
+	}
+
+	// Resuming again does not report anything
+	e.ResumeAgents()
+	if got := drainEvents(events); len(got) != 0 {
+		t.Errorf("second ResumeAgents() emitted %d events, want none", len(got))
+	}
+}
+
+func TestRelaunchCrashWindow(t *testing.T) {
+	e, f, pid := newAgentTestEngine(t, config.StrategyKillRelaunch, false, 10*time.Second)
+	cfg := e.settings()
+	cfg.AgentCrashWindow = config.Duration(3 * time.Second)
+	e.SetConfig(cfg)
+
+	e.checkAgentProcesses()
+	launched := relaunchOf(t, e, pid).pid
+	if launched == 0 {
+		t.Fatal("the relaunched helper has no PID")
+	}
+	processes, err := f.FindProcessesByNames([]string{d2r.AgentProcessName})
+	if err != nil || len(processes) != 1 {
+		t.Fatalf("FindProcessesByNames() = %v, %v", processes, err)
+	}
+	e.processStarted(f.ReadProcessDetails(processes[0]))
+
+	// Running for less than the crash window keeps it supervised
+	updateRelaunch(e, pid, func(r *relaunch) {
+		r.launchedAt = time.Now().Add(-2 * time.Second)
+	})
+	e.checkRelaunches()
+	relaunchOf(t, e, pid)
+
+	// Exiting within the crash window counts as a crash
+	f.RemoveProcess(launched)
+	e.processExited(processes[0])
+	e.checkRelaunches()
+	r := relaunchOf(t, e, pid)
+	if r.failures != 1 || !r.launchedAt.IsZero() {
+		t.Fatalf("failures = %d, launchedAt = %v after a crash, want one failure and a pending retry", r.failures, r.launchedAt)
+	}
+
+	// Running for the crash window confirms the relaunch
+	updateRelaunch(e, pid, func(r *relaunch) {
+		r.retryAt = time.Now()
+	})
+	e.checkRelaunches()
+	processes, err = f.FindProcessesByNames([]string{d2r.AgentProcessName})
+	if err != nil || len(processes) != 1 {
+		t.Fatalf("FindProcessesByNames() = %v, %v", processes, err)
+	}
+	e.processStarted(f.ReadProcessDetails(processes[0]))
+	updateRelaunch(e, pid, func(r *relaunch) {
+		r.launchedAt = time.Now().Add(-3 * time.Second)
+	})
+	e.checkRelaunches()
+
+	e.mu.Lock()
+	_, supervised := e.relaunches[pid]
+	e.mu.Unlock()
+	if supervised {
+		t.Error("the relaunch is still supervised after running for the crash window")
+	}
+	if e.AgentsPaused() {
+		t.Error("AgentsPaused() = true after one crash")
+	}
+}
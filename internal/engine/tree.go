@@ -0,0 +1,159 @@
+package engine
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// ProcessKind is the role of a process in the process tree
+type ProcessKind int
+
+const (
+	// ProcessLauncher is a launcher such as Battle.net.exe
+	ProcessLauncher ProcessKind = iota
+	// ProcessGame is a game instance such as D2R.exe
+	ProcessGame
+	// ProcessHelper is a launcher helper such as Agent.exe
+	ProcessHelper
+)
+
+// String returns the name of the kind
+func (k ProcessKind) String() string {
+	switch k {
+	case ProcessLauncher:
+		return "launcher"
+	case ProcessGame:
+		return "game"
+	case ProcessHelper:
+		return "helper"
+	default:
+		return "unknown"
+	}
+}
+
+// ProcessNode is a process in the process tree
+type ProcessNode struct {
+	PID       uint32
+	ParentPID uint32
+	Name      string
+	Kind      ProcessKind
+
+	// StartTime is when the process was started; zero when unknown
+	StartTime time.Time
+
+	// Children are the processes started by this one, ordered by PID
+	Children []*ProcessNode
+}
+
+// BuildProcessTree arranges processes by their parent PIDs and returns the
+// roots ordered by PID. A process is a root when its parent is not listed,
+// when the parent was started after it, which means the parent PID was
+// reused, or when linking it to its parent would close a cycle; processes
+// are linked in the given order. Only the first process with a given PID
+// is used, and the Children of the given processes are ignored.
+func BuildProcessTree(processes []ProcessNode) []*ProcessNode {
+	nodes := make(map[uint32]*ProcessNode, len(processes))
+	ordered := make([]*ProcessNode, 0, len(processes))
+	for _, p := range processes {
+		if _, ok := nodes[p.PID]; ok {
+			continue
+		}
+		node := p
+		node.Children = nil
+		nodes[p.PID] = &node
+		ordered = append(ordered, &node)
+	}
+
+	// linked holds the parent each process was linked to so far
+	linked := make(map[*ProcessNode]*ProcessNode, len(ordered))
+	var roots []*ProcessNode
+	for _, node := range ordered {
+		parent, ok := nodes[node.ParentPID]
+		if ok && isParent(parent, node, linked) {
+			parent.Children = append(parent.Children, node)
+			linked[node] = parent
+		} else {
+			roots = append(roots, node)
+		}
+	}
+
+	sortNodes(roots)
+	return roots
+}
+
+// isParent reports whether parent really started child. The parent must
+// not be younger than the child, and linking them must not close a cycle
+// with the links made so far, which reused PIDs without known start times
+// could otherwise cause.
+func isParent(parent, child *ProcessNode, linked map[*ProcessNode]*ProcessNode) bool {
+	if !parent.StartTime.IsZero() && !child.StartTime.IsZero() && parent.StartTime.After(child.StartTime) {
+		return false
+	}
+
+	// Walk up the linked ancestors of the parent; reaching the child would
+	// close a cycle. The links made so far never form one, so this ends.
+	for p := parent; p != nil; p = linked[p] {
+		if p == child {
+			return false
+		}
+	}
+	return true
+}
+
+// sortNodes orders nodes and, recursively, their children by PID
+func sortNodes(nodes []*ProcessNode) {
+	slices.SortFunc(nodes, func(a, b *ProcessNode) int {
+		return cmp.Compare(a.PID, b.PID)
+	})
+	for _, node := range nodes {
+		sortNodes(node.Children)
+	}
+}
+
+// ProcessNodes returns the processes of the status with the given kinds as
+// tree nodes: the launchers, the game instances that have not exited and
+// the launcher helpers. No kinds returns all of them.
+func (s Status) ProcessNodes(kinds ...ProcessKind) []ProcessNode {
+	want := func(kind ProcessKind) bool {
+		return len(kinds) == 0 || slices.Contains(kinds, kind)
+	}
+
+	var nodes []ProcessNode
+	if want(ProcessLauncher) {
+		for _, p := range s.Launchers {
+			nodes = append(nodes, p.node(ProcessLauncher))
+		}
+	}
+	if want(ProcessGame) {
+		for _, inst := range s.GameProcesses {
+			if inst.State == InstanceExited {
+				continue
+			}
+			nodes = append(nodes, ProcessNode{
+				PID:       inst.PID,
+				ParentPID: inst.ParentPID,
+				Name:      inst.ProcessName,
+				Kind:      ProcessGame,
+				StartTime: inst.Created,
+			})
+		}
+	}
+	if want(ProcessHelper) {
+		for _, p := range s.AgentProcesses {
+			nodes = append(nodes, p.node(ProcessHelper))
+		}
+	}
+	return nodes
+}
+
+// node returns the process as a tree node of the given kind
+func (p ProcessStatus) node(kind ProcessKind) ProcessNode {
+	return ProcessNode{
+		PID:       p.PID,
+		ParentPID: p.ParentPID,
+		Name:      p.Name,
+		Kind:      kind,
+		StartTime: p.StartTime,
+	}
+}
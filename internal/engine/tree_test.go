@@ -0,0 +1,178 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// formatTree renders a process tree as "pid(child child(...))" for comparison
+func formatTree(nodes []*ProcessNode) string {
+	parts := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		part := fmt.Sprint(node.PID)
+		if len(node.Children) > 0 {
+			part += "(" + formatTree(node.Children) + ")"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestBuildProcessTree(t *testing.T) {
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time {
+		return base.Add(time.Duration(minutes) * time.Minute)
+	}
+
+	tests := []struct {
+		name      string
+		processes []ProcessNode
+		want      string
+	}{
+		{
+			name: "empty",
+			want: "",
+		},
+		{
+			name: "launcher with games and helper",
+			processes: []ProcessNode{
+				{PID: 300, ParentPID: 100, Kind: ProcessGame, StartTime: at(2)},
+				{PID: 200, ParentPID: 100, Kind: ProcessHelper, StartTime: at(1)},
+				{PID: 100, ParentPID: 4, Kind: ProcessLauncher, StartTime: at(0)},
+				{PID: 250, ParentPID: 100, Kind: ProcessGame, StartTime: at(3)},
+			},
+			want: "100(200 250 300)",
+		},
+		{
+			name: "nested reparenting",
+			processes: []ProcessNode{
+				{PID: 30, ParentPID: 20, StartTime: at(2)},
+				{PID: 20, ParentPID: 10, StartTime: at(1)},
+				{PID: 10, StartTime: at(0)},
+			},
+			want: "10(20(30))",
+		},
+		{
+			name: "parent exited",
+			processes: []ProcessNode{
+				{PID: 100, ParentPID: 4, Kind: ProcessLauncher, StartTime: at(0)},
+				{PID: 300, ParentPID: 99, Kind: ProcessGame, StartTime: at(1)},
+			},
+			want: "100 300",
+		},
+		{
+			name: "parent PID reused by a younger process",
+			processes: []ProcessNode{
+				{PID: 100, ParentPID: 4, Kind: ProcessLauncher, StartTime: at(5)},
+				{PID: 300, ParentPID: 100, Kind: ProcessGame, StartTime: at(1)},
+			},
+			want: "100 300",
+		},
+		{
+			name: "same start time",
+			processes: []ProcessNode{
+				{PID: 100, StartTime: at(1)},
+				{PID: 300, ParentPID: 100, StartTime: at(1)},
+			},
+			want: "100(300)",
+		},
+		{
+			name: "unknown start times",
+			processes: []ProcessNode{
+				{PID: 100},
+				{PID: 300, ParentPID: 100, StartTime: at(1)},
+			},
+			want: "100(300)",
+		},
+		{
+			name: "own parent",
+			processes: []ProcessNode{
+				{PID: 100, ParentPID: 100},
+			},
+			want: "100",
+		},
+		{
+			name: "two process cycle",
+			processes: []ProcessNode{
+				{PID: 100, ParentPID: 200},
+				{PID: 200, ParentPID: 100},
+			},
+			want: "200(100)",
+		},
+		{
+			name: "three process cycle",
+			processes: []ProcessNode{
+				{PID: 30, ParentPID: 20},
+				{PID: 10, ParentPID: 30},
+				{PID: 20, ParentPID: 10},
+			},
+			want: "20(30(10))",
+		},
+		{
+			name: "duplicate PID",
+			processes: []ProcessNode{
+				{PID: 100, Name: "first"},
+				{PID: 100, Name: "second"},
+				{PID: 300, ParentPID: 100},
+			},
+			want: "100(300)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roots := BuildProcessTree(tt.processes)
+			if got := formatTree(roots); got != tt.want {
+				t.Errorf("BuildProcessTree() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildProcessTreeKeepsInput(t *testing.T) {
+	child := &ProcessNode{PID: 999}
+	processes := []ProcessNode{
+		{PID: 100, Name: "first", Children: []*ProcessNode{child}},
+		{PID: 100, Name: "second"},
+		{PID: 300, ParentPID: 100},
+	}
+
+	roots := BuildProcessTree(processes)
+
+	if len(roots) != 1 || roots[0].Name != "first" {
+		t.Fatalf("roots = %+v, want the first process with PID 100", roots)
+	}
+	if got := formatTree(roots); got != "100(300)" {
+		t.Errorf("BuildProcessTree() = %q, want the given children ignored", got)
+	}
+	if len(processes[0].Children) != 1 || processes[0].Children[0] != child {
+		t.Error("BuildProcessTree() modified the given processes")
+	}
+}
+
+func TestStatusProcessNodes(t *testing.T) {
+	status := Status{
+		Launchers:      []ProcessStatus{{PID: 100, Name: "Battle.net.exe"}},
+		AgentProcesses: []ProcessStatus{{PID: 200, ParentPID: 100, Name: "Agent.exe"}},
+		GameProcesses: []InstanceStatus{
+			{InstanceKey: InstanceKey{PID: 300}, ParentPID: 100, ProcessName: "D2R.exe", State: InstanceVerified},
+			{InstanceKey: InstanceKey{PID: 400}, ParentPID: 100, ProcessName: "D2R.exe", State: InstanceExited},
+		},
+	}
+
+	if got := formatTree(BuildProcessTree(status.ProcessNodes())); got != "100(200 300)" {
+		t.Errorf("tree of all kinds = %q, want %q", got, "100(200 300)")
+	}
+	if got := formatTree(BuildProcessTree(status.ProcessNodes(ProcessLauncher, ProcessGame))); got != "100(300)" {
+		t.Errorf("tree of launchers and games = %q, want %q", got, "100(300)")
+	}
+
+	for _, node := range status.ProcessNodes() {
+		want := map[uint32]ProcessKind{100: ProcessLauncher, 200: ProcessHelper, 300: ProcessGame}[node.PID]
+		if node.Kind != want {
+			t.Errorf("PID %d has kind %v, want %v", node.PID, node.Kind, want)
+		}
+	}
+}
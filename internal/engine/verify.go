@@ -0,0 +1,147 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/chenwei791129/multiablo/internal/handle"
+	"github.com/chenwei791129/multiablo/internal/i18n"
+)
+
+// verifyClosed confirms that the single-instance handles of a game process
+// are gone. after is a snapshot taken once the handles listed in before
+// were closed; a matching handle left in it is an error. The named objects
+// of the closed handles are then looked up, since another process can keep
+// them alive, and those that still exist are reported. Handles of the rule
+// types whose names could not be resolved in after also fail verification.
+func (e *Engine) verifyClosed(before, after *handle.Snapshot, pid uint32, processName string, matcher *handle.Matcher) error {
+	handles, err := after.Find(pid, matcher)
+	if err != nil {
+		return fmt.Errorf("failed to verify: %w", err)
+	}
+	if len(handles) > 0 {
+		return fmt.Errorf("%d single-instance handle(s) still open", len(handles))
+	}
+	if unresolved := after.Unresolved(pid, matcher.Types()); unresolved > 0 {
+		return fmt.Errorf("failed to verify: the names of %d handle(s) could not be resolved", unresolved)
+	}
+
+	closed, err := before.Find(pid, matcher)
+	if err == nil {
+		e.probeClosedObjects(pid, processName, closed)
+	}
+	return nil
+}
+
+// probeClosedObjects looks up the named objects of closed handles and emits
+// EventHandleBlocked for each one that still exists, with the processes that
+// hold it. Game instances that are still being handled are not reported as
+// holders, since their handles are about to be closed as well.
+func (e *Engine) probeClosedObjects(pid uint32, processName string, closed []handle.HandleInfo) {
+	probed := make(map[[2]string]bool)
+	for _, h := range closed {
+		object := [2]string{h.TypeName, h.Name}
+		if probed[object] {
+			continue
+		}
+		probed[object] = true
+
+		probe, err := e.handles.ProbeObject(h.TypeName, h.Name)
+		if errors.Is(err, handle.ErrProbeUnsupported) {
+			continue
+		}
+		if err != nil {
+			e.emit(Event{
+				Type:        EventError,
+				ProcessName: processName,
+				PID:         pid,
+				Err:         err,
+				Message:     fmt.Sprintf(i18n.Get("Could not check whether %s still exists: %v"), h.Name, err),
+			})
+			continue
+		}
+		if !probe.Exists {
+			continue
+		}
+
+		pending := e.pendingInstances(pid)
+		var holders []uint32
+		for _, holder := range probe.Holders {
+			if !pending[holder] {
+				holders = append(holders, holder)
+			}
+		}
+
+		var message string
+		switch {
+		case len(holders) > 0:
+			message = fmt.Sprintf(i18n.Get("%s still exists after closing it in %s (PID: %d), blocked by PID %s"),
+				h.Name, processName, pid, e.describePIDs(holders))
+		case len(probe.Holders) > 0 || len(pending) > 0:
+			// Only held by game instances that are handled next
+			continue
+		default:
+			message = fmt.Sprintf(i18n.Get("%s still exists after closing it in %s (PID: %d), held by a process that could not be identified"),
+				h.Name, processName, pid)
+		}
+
+		e.emit(Event{
+			Type:        EventHandleBlocked,
+			ProcessName: processName,
+			PID:         pid,
+			BlockedBy:   holders,
+			Message:     message,
+		})
+	}
+}
+
+// pendingInstances returns the PIDs of the running game instances, other
+// than exclude, whose handles have not been closed and verified yet
+func (e *Engine) pendingInstances(exclude uint32) map[uint32]bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	pending := make(map[uint32]bool)
+	for key, inst := range e.instances {
+		if key.PID == exclude {
+			continue
+		}
+		switch inst.State {
+		case InstanceDetected, InstanceScanning, InstanceHandleClosed, InstanceRetrying:
+			pending[key.PID] = true
+		}
+	}
+	return pending
+}
+
+// describePIDs lists PIDs with the names of the monitored processes among them
+func (e *Engine) describePIDs(pids []uint32) string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	parts := make([]string, 0, len(pids))
+	for _, pid := range pids {
+		part := strconv.FormatUint(uint64(pid), 10)
+		if name := e.trackedName(pid); name != "" {
+			part += " (" + name + ")"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// trackedName returns the name of a monitored process, or "" when it is
+// not monitored (caller must hold e.mu)
+func (e *Engine) trackedName(pid uint32) string {
+	for key, inst := range e.instances {
+		if key.PID == pid && inst.State != InstanceExited {
+			return inst.ProcessName
+		}
+	}
+	if agent, ok := e.agentProcesses[pid]; ok {
+		return agent.Name
+	}
+	return ""
+}
@@ -0,0 +1,155 @@
+package engine
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chenwei791129/multiablo/internal/game"
+	"github.com/chenwei791129/multiablo/internal/handle"
+	"github.com/chenwei791129/multiablo/internal/process"
+	"github.com/chenwei791129/multiablo/pkg/d2r"
+)
+
+func TestVerifyClosed(t *testing.T) {
+	const pid, holder = 100, 200
+
+	tests := []struct {
+		name string
+		// setup changes the handle tables after the handle of pid was closed
+		setup func(e *Engine, pf *process.Fake, hf *handle.Fake)
+		// keepOpen leaves the handle of pid open
+		keepOpen  bool
+		wantErr   string
+		blocked   bool
+		blockedBy []uint32
+	}{
+		{
+			name: "gone",
+		},
+		{
+			name:     "still open",
+			keepOpen: true,
+			wantErr:  "still open",
+		},
+		{
+			name: "name not resolved",
+			setup: func(_ *Engine, _ *process.Fake, hf *handle.Fake) {
+				hf.SetNameResolver(handle.NewNameResolver(10*time.Millisecond, 1))
+				hf.SetHandles(pid, handle.FakeHandle{Value: 0x8, TypeName: "Event", Name: "slow", NameDelay: time.Second})
+			},
+			wantErr: "could not be resolved",
+		},
+		{
+			name: "held by an unknown process",
+			setup: func(_ *Engine, _ *process.Fake, hf *handle.Fake) {
+				hf.KeepObject(testSingleInstance.TypeName, testSingleInstance.Name)
+			},
+			blocked: true,
+		},
+		{
+			name: "held by another process",
+			setup: func(_ *Engine, _ *process.Fake, hf *handle.Fake) {
+				hf.SetHandles(holder, testSingleInstance)
+			},
+			blocked:   true,
+			blockedBy: []uint32{holder},
+		},
+		{
+			name: "held by an instance handled next",
+			setup: func(e *Engine, pf *process.Fake, hf *handle.Fake) {
+				hf.SetHandles(holder, testSingleInstance)
+				e.trackInstance(process.ProcessInfo{PID: holder, Name: d2r.ProcessName, CreationTime: pf.Now()}, game.D2RID)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pf := process.NewFake(time.Now())
+			hf := handle.NewFake()
+			e := New(Options{Processes: pf, Handles: hf})
+			events, unsubscribe := e.Subscribe()
+			defer unsubscribe()
+			matcher, err := e.handleMatcher()
+			if err != nil {
+				t.Fatalf("handleMatcher() error = %v", err)
+			}
+
+			hf.SetHandles(pid, testSingleInstance)
+			before, err := hf.Snapshot([]uint32{pid}, matcher.Types())
+			if err != nil {
+				t.Fatalf("Snapshot() error = %v", err)
+			}
+			if !tt.keepOpen {
+				hf.SetHandles(pid)
+			}
+			if tt.setup != nil {
+				tt.setup(e, pf, hf)
+			}
+			after, err := hf.Snapshot([]uint32{pid}, matcher.Types())
+			if err != nil {
+				t.Fatalf("Snapshot() error = %v", err)
+			}
+
+			err = e.verifyClosed(before, after, pid, d2r.ProcessName, matcher)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("verifyClosed() error = %v, want nil", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("verifyClosed() error = %v, want %q", err, tt.wantErr)
+			}
+
+			var blocked []Event
+			for _, event := range drainEvents(events) {
+				if event.Type == EventHandleBlocked {
+					blocked = append(blocked, event)
+				}
+			}
+			if !tt.blocked {
+				if len(blocked) != 0 {
+					t.Errorf("blocked events = %+v, want none", blocked)
+				}
+				return
+			}
+			if len(blocked) != 1 {
+				t.Fatalf("blocked events = %+v, want one", blocked)
+			}
+			if blocked[0].PID != pid || !slices.Equal(blocked[0].BlockedBy, tt.blockedBy) ||
+				!strings.Contains(blocked[0].Message, testSingleInstance.Name) {
+				t.Errorf("blocked event = %+v, want PID %d blocked by %v", blocked[0], pid, tt.blockedBy)
+			}
+		})
+	}
+}
+
+func TestCloseHandlesVerifies(t *testing.T) {
+	pf := process.NewFake(time.Now())
+	hf := handle.NewFake()
+	e := New(Options{Processes: pf, Handles: hf})
+	events, unsubscribe := e.Subscribe()
+	defer unsubscribe()
+
+	pid := pf.AddProcess(process.FakeProcess{Name: d2r.ProcessName, StartTime: pf.Now()})
+	hf.SetHandles(pid, testSingleInstance)
+	hf.KeepObject(testSingleInstance.TypeName, testSingleInstance.Name)
+
+	count, err := e.CloseHandles(pid)
+	if count != 1 || err != nil {
+		t.Fatalf("CloseHandles() = %d, %v, want 1, nil", count, err)
+	}
+	if got := hf.Snapshots(); got != 2 {
+		t.Errorf("took %d snapshots, want one to close and one to verify", got)
+	}
+
+	blocked := 0
+	for _, event := range drainEvents(events) {
+		if event.Type == EventHandleBlocked {
+			blocked++
+		}
+	}
+	if blocked != 1 {
+		t.Errorf("%d blocked events, want 1 for the object kept alive", blocked)
+	}
+}
@@ -0,0 +1,154 @@
+// Package game describes the games whose single-instance handles Multiablo
+// closes. A definition lists the game's process names, the handles it uses
+// to prevent multiple instances and its launcher helper processes.
+package game
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/chenwei791129/multiablo/internal/handle"
+	"github.com/chenwei791129/multiablo/pkg/d2r"
+)
+
+// D2RID is the ID of the built-in Diablo II: Resurrected definition
+const D2RID = "d2r"
+
+// Helper is a launcher helper process, such as Agent.exe, that is
+// terminated and relaunched while the game runs
+type Helper struct {
+	// ProcessName is the executable name, e.g. "Agent.exe"
+	ProcessName string `json:"process_name"`
+
+	// DefaultPath is used to relaunch the helper when the path of the running process is unknown
+	DefaultPath string `json:"default_path"`
+}
+
+// Definition describes a game
+type Definition struct {
+	// ID identifies the definition in the enabled_games setting
+	ID string `json:"id"`
+
+	// Name is shown to the user; empty shows ID
+	Name string `json:"name"`
+
+	// ProcessNames are the executable names of the game, e.g. "D2R.exe"
+	ProcessNames []string `json:"process_names"`
+
+	// HandleMatchRules select the handles the game uses to prevent multiple
+	// instances; a handle matching any rule is closed
+	HandleMatchRules []handle.MatchRule `json:"handle_match_rules"`
+
+	// Helpers are the launcher helper processes of the game
+	Helpers []Helper `json:"helpers"`
+
+	// Launchers are the executable names of the launchers that start the
+	// game and its helpers, e.g. "Battle.net.exe"; they are only shown
+	Launchers []string `json:"launchers"`
+}
+
+// D2R returns the built-in Diablo II: Resurrected definition
+func D2R() Definition {
+	return Definition{
+		ID:           D2RID,
+		Name:         "Diablo II: Resurrected",
+		ProcessNames: []string{d2r.ProcessName},
+		HandleMatchRules: []handle.MatchRule{{
+			Type:             "Event",
+			Match:            handle.MatchContains,
+			Pattern:          d2r.SingleInstanceEventName,
+			NormalizeSession: true,
+		}},
+		Helpers: []Helper{{
+			ProcessName: d2r.AgentProcessName,
+			DefaultPath: d2r.DefaultAgentPath,
+		}},
+		Launchers: []string{d2r.LauncherProcessName},
+	}
+}
+
+// Builtin returns the definitions shipped with Multiablo
+func Builtin() []Definition {
+	return []Definition{D2R()}
+}
+
+// Label returns the name to show for the definition
+func (d Definition) Label() string {
+	if strings.TrimSpace(d.Name) != "" {
+		return d.Name
+	}
+	return d.ID
+}
+
+// HasProcess reports whether name is one of the game's process names (case-insensitive)
+func (d Definition) HasProcess(name string) bool {
+	for _, p := range d.ProcessNames {
+		if strings.EqualFold(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasLauncher reports whether name is one of the game's launchers (case-insensitive)
+func (d Definition) HasLauncher(name string) bool {
+	for _, l := range d.Launchers {
+		if strings.EqualFold(l, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Helper returns the helper with the given process name (case-insensitive)
+func (d Definition) Helper(name string) (Helper, bool) {
+	for _, h := range d.Helpers {
+		if strings.EqualFold(h.ProcessName, name) {
+			return h, true
+		}
+	}
+	return Helper{}, false
+}
+
+// Validate checks the definition and reports all problems at once
+func (d Definition) Validate() error {
+	var errs []error
+
+	if strings.TrimSpace(d.ID) == "" || strings.ContainsAny(d.ID, " \t") {
+		errs = append(errs, fmt.Errorf("id: must be a non-empty word (got %q)", d.ID))
+	}
+
+	if len(d.ProcessNames) == 0 {
+		errs = append(errs, errors.New("process_names: at least one process name is required"))
+	}
+	for i, name := range d.ProcessNames {
+		if strings.TrimSpace(name) == "" {
+			errs = append(errs, fmt.Errorf("process_names[%d]: must not be empty", i))
+		}
+	}
+
+	if _, err := handle.NewMatcher(d.HandleMatchRules); err != nil {
+		errs = append(errs, fmt.Errorf("handle_match_rules: %w", err))
+	}
+
+	for i, h := range d.Helpers {
+		if strings.TrimSpace(h.ProcessName) == "" {
+			errs = append(errs, fmt.Errorf("helpers[%d].process_name: must not be empty", i))
+		}
+		if d.HasProcess(h.ProcessName) {
+			errs = append(errs, fmt.Errorf("helpers[%d].process_name: %q is also a game process", i, h.ProcessName))
+		}
+	}
+
+	for i, name := range d.Launchers {
+		if strings.TrimSpace(name) == "" {
+			errs = append(errs, fmt.Errorf("launchers[%d]: must not be empty", i))
+		}
+		if _, ok := d.Helper(name); ok || d.HasProcess(name) {
+			errs = append(errs, fmt.Errorf("launchers[%d]: %q is also a game or helper process", i, name))
+		}
+	}
+
+	return errors.Join(errs...)
+}
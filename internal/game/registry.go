@@ -0,0 +1,128 @@
+package game
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// Registry holds the known game definitions by ID
+type Registry struct {
+	definitions []Definition
+}
+
+// NewRegistry creates a registry of the given definitions.
+// Every definition must be valid and have a unique ID.
+func NewRegistry(definitions []Definition) (*Registry, error) {
+	r := &Registry{}
+	var errs []error
+	for _, d := range definitions {
+		if err := r.add(d); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// BuiltinRegistry returns a registry of the built-in definitions
+func BuiltinRegistry() *Registry {
+	return &Registry{definitions: Builtin()}
+}
+
+// Load returns a registry of the built-in definitions and the definitions
+// in the *.json files of dir. A missing directory is not an error. Invalid
+// files are skipped and reported in the returned error, together with a
+// registry of the definitions that could be loaded.
+func Load(dir string) (*Registry, error) {
+	r := BuiltinRegistry()
+	if dir == "" {
+		return r, nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return r, fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+	slices.Sort(paths)
+
+	var errs []error
+	for _, path := range paths {
+		d, err := loadFile(path)
+		if err == nil {
+			err = r.add(d)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid game definition %s: %w", path, err))
+		}
+	}
+	return r, errors.Join(errs...)
+}
+
+// loadFile reads a definition from a JSON file
+func loadFile(path string) (Definition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Definition{}, err
+	}
+
+	var d Definition
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&d); err != nil {
+		return Definition{}, fmt.Errorf("failed to decode: %w", err)
+	}
+	return d, nil
+}
+
+// add validates a definition and adds it unless its ID is taken
+func (r *Registry) add(d Definition) error {
+	if err := d.Validate(); err != nil {
+		return err
+	}
+	if _, ok := r.Get(d.ID); ok {
+		return fmt.Errorf("id: %q is already defined", d.ID)
+	}
+	r.definitions = append(r.definitions, d)
+	return nil
+}
+
+// Definitions returns all definitions, built-in ones first
+func (r *Registry) Definitions() []Definition {
+	return slices.Clone(r.definitions)
+}
+
+// Get returns the definition with the given ID
+func (r *Registry) Get(id string) (Definition, bool) {
+	for _, d := range r.definitions {
+		if d.ID == id {
+			return d, true
+		}
+	}
+	return Definition{}, false
+}
+
+// Enabled returns the definitions with the given IDs in registry order,
+// and the IDs that are not defined
+func (r *Registry) Enabled(ids []string) ([]Definition, []string) {
+	var enabled []Definition
+	for _, d := range r.definitions {
+		if slices.Contains(ids, d.ID) {
+			enabled = append(enabled, d)
+		}
+	}
+
+	var unknown []string
+	for _, id := range ids {
+		if _, ok := r.Get(id); !ok {
+			unknown = append(unknown, id)
+		}
+	}
+	return enabled, unknown
+}
@@ -0,0 +1,150 @@
+package game
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/chenwei791129/multiablo/internal/handle"
+)
+
+// testDefinition returns a valid definition with the given ID
+func testDefinition(id string) Definition {
+	return Definition{
+		ID:           id,
+		ProcessNames: []string{id + ".exe"},
+		HandleMatchRules: []handle.MatchRule{{
+			Type:    "Mutant",
+			Match:   handle.MatchExact,
+			Pattern: id + " Single Instance",
+		}},
+	}
+}
+
+// ids returns the IDs of definitions in order
+func ids(definitions []Definition) []string {
+	var result []string
+	for _, d := range definitions {
+		result = append(result, d.ID)
+	}
+	return result
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"b-valid.json": `{"id": "other", "name": "Other Game", "process_names": ["Other.exe"],
+			"handle_match_rules": [{"type": "Mutant", "match": "exact", "pattern": "Other Single Instance"}],
+			"helpers": [{"process_name": "OtherHelper.exe", "default_path": "C:\\Other\\OtherHelper.exe"}],
+			"launchers": ["OtherLauncher.exe"]}`,
+		"a-duplicate.json": `{"id": "d2r", "process_names": ["D2R.exe"],
+			"handle_match_rules": [{"type": "Event", "match": "exact", "pattern": "x"}]}`,
+		"c-unknown-field.json": `{"id": "third", "process_names": ["Third.exe"], "colour": "red",
+			"handle_match_rules": [{"type": "Event", "match": "exact", "pattern": "x"}]}`,
+		"d-invalid.json": `{"id": "fourth", "process_names": []}`,
+		"ignored.txt":    `not a definition`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := Load(dir)
+	if err == nil {
+		t.Fatal("Load() error = nil, want the invalid files reported")
+	}
+	for _, name := range []string{"a-duplicate.json", "c-unknown-field.json", "d-invalid.json"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("Load() error does not name %s: %v", name, err)
+		}
+	}
+	if strings.Contains(err.Error(), "b-valid.json") {
+		t.Errorf("Load() error names the valid file: %v", err)
+	}
+
+	if got, want := ids(r.Definitions()), []string{D2RID, "other"}; !slices.Equal(got, want) {
+		t.Fatalf("Definitions() = %v, want %v", got, want)
+	}
+	other, ok := r.Get("other")
+	if !ok {
+		t.Fatal("Get(other) found nothing")
+	}
+	if other.Label() != "Other Game" || !other.HasProcess("other.EXE") || !other.HasLauncher("otherlauncher.exe") {
+		t.Errorf("loaded definition = %+v", other)
+	}
+	if h, ok := other.Helper("OTHERHELPER.EXE"); !ok || h.DefaultPath != `C:\Other\OtherHelper.exe` {
+		t.Errorf("Helper() = %+v, %v", h, ok)
+	}
+	if d2r, _ := r.Get(D2RID); d2r.Label() != D2R().Name {
+		t.Errorf("the built-in D2R definition was replaced: %+v", d2r)
+	}
+}
+
+func TestLoadMissingDir(t *testing.T) {
+	for _, dir := range []string{"", filepath.Join(t.TempDir(), "missing")} {
+		r, err := Load(dir)
+		if err != nil {
+			t.Errorf("Load(%q) error = %v", dir, err)
+		}
+		if got, want := ids(r.Definitions()), []string{D2RID}; !slices.Equal(got, want) {
+			t.Errorf("Load(%q) definitions = %v, want %v", dir, got, want)
+		}
+	}
+}
+
+func TestNewRegistry(t *testing.T) {
+	if _, err := NewRegistry(append(Builtin(), testDefinition("other"), testDefinition("other"))); err == nil {
+		t.Error("NewRegistry() with a duplicate ID succeeded")
+	}
+
+	r, err := NewRegistry(append(Builtin(), testDefinition("other"), testDefinition("third")))
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+	enabled, unknown := r.Enabled([]string{"third", "missing", D2RID})
+	if got, want := ids(enabled), []string{D2RID, "third"}; !slices.Equal(got, want) {
+		t.Errorf("Enabled() = %v, want %v in registry order", got, want)
+	}
+	if want := []string{"missing"}; !slices.Equal(unknown, want) {
+		t.Errorf("Enabled() unknown = %v, want %v", unknown, want)
+	}
+}
+
+func TestDefinitionValidate(t *testing.T) {
+	if err := D2R().Validate(); err != nil {
+		t.Fatalf("D2R().Validate() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(d *Definition)
+	}{
+		{name: "empty ID", modify: func(d *Definition) { d.ID = "" }},
+		{name: "ID with a space", modify: func(d *Definition) { d.ID = "two words" }},
+		{name: "no process names", modify: func(d *Definition) { d.ProcessNames = nil }},
+		{name: "empty process name", modify: func(d *Definition) { d.ProcessNames = append(d.ProcessNames, " ") }},
+		{name: "no match rules", modify: func(d *Definition) { d.HandleMatchRules = nil }},
+		{name: "empty helper name", modify: func(d *Definition) { d.Helpers = []Helper{{}} }},
+		{name: "helper is a game process", modify: func(d *Definition) {
+			d.Helpers = []Helper{{ProcessName: strings.ToLower(d.ProcessNames[0])}}
+		}},
+		{name: "launcher is a helper", modify: func(d *Definition) {
+			d.Helpers = []Helper{{ProcessName: "Helper.exe"}}
+			d.Launchers = []string{"helper.exe"}
+		}},
+		{name: "empty launcher", modify: func(d *Definition) { d.Launchers = []string{""} }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := testDefinition("game")
+			tt.modify(&d)
+			if err := d.Validate(); err == nil {
+				t.Errorf("Validate() of %+v succeeded, want an error", d)
+			}
+		})
+	}
+}
@@ -2,9 +2,13 @@
 package gui
 
 import (
+	"fmt"
+
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 
+	"github.com/chenwei791129/multiablo/internal/config"
+	"github.com/chenwei791129/multiablo/internal/game"
 	"github.com/chenwei791129/multiablo/internal/i18n"
 )
 
@@ -22,22 +26,52 @@ func AppTitle() string {
 type App struct {
 	fyneApp fyne.App
 	window  *MainWindow
+
+	// Settings loaded from configPath
+	cfg        config.Config
+	configPath string
+	configErr  error
+
+	// Game definitions loaded from the games directory next to configPath
+	games    *game.Registry
+	gamesErr error
 }
 
-// NewApp creates a new GUI application
-func NewApp() *App {
-	// Initialize i18n with system language detection
-	i18n.Init("")
+// NewApp creates a new GUI application using the settings file at configPath
+// and the game definitions in the games directory next to it.
+// An empty configPath uses the default settings and the built-in games.
+func NewApp(configPath string) *App {
+	cfg := config.Default()
+	games := game.BuiltinRegistry()
+	var cfgErr, gamesErr error
+	if configPath != "" {
+		cfg, cfgErr = config.Load(configPath)
+		games, gamesErr = game.Load(config.GamesDir(configPath))
+	}
+
+	// Initialize i18n with the configured language, or system language detection
+	i18n.Init(cfg.Language)
 
 	a := app.NewWithID(AppID)
 	return &App{
-		fyneApp: a,
+		fyneApp:    a,
+		cfg:        cfg,
+		configPath: configPath,
+		configErr:  cfgErr,
+		games:      games,
+		gamesErr:   gamesErr,
 	}
 }
 
 // Run starts the application
 func (a *App) Run() {
-	a.window = NewMainWindow(a.fyneApp)
+	a.window = NewMainWindow(a.fyneApp, a.cfg, a.configPath, a.games)
+	if a.configErr != nil {
+		a.window.AppendLog(fmt.Sprintf(i18n.Get("Failed to load settings, using defaults: %v"), a.configErr))
+	}
+	if a.gamesErr != nil {
+		a.window.AppendLog(fmt.Sprintf(i18n.Get("Some game definitions could not be loaded: %v"), a.gamesErr))
+	}
 	a.window.Show()
 	a.window.StartMonitoringAutomatically()
 	a.fyneApp.Run()
@@ -0,0 +1,220 @@
+package gui
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/chenwei791129/multiablo/internal/engine"
+	"github.com/chenwei791129/multiablo/internal/handle"
+	"github.com/chenwei791129/multiablo/internal/i18n"
+)
+
+const (
+	handlesWidth  = 760
+	handlesHeight = 520
+)
+
+// handlesWindow lists the handles of a process for debugging
+type handlesWindow struct {
+	parent *MainWindow
+	window fyne.Window
+	engine *engine.Engine
+
+	// handles is the result of the last refresh
+	handles []handle.HandleInfo
+
+	pidEntry   *widget.SelectEntry
+	typeSelect *widget.Select
+	nameEntry  *widget.Entry
+	refreshBtn *widget.Button
+	table      *widget.Table
+	status     *widget.Label
+}
+
+// showHandles opens the handle inspector window
+func (w *MainWindow) showHandles() {
+	cfg := w.config()
+	h := &handlesWindow{
+		parent: w,
+		engine: engine.New(engine.Options{Config: &cfg, Games: w.games}),
+	}
+	h.window = w.app.NewWindow(i18n.Get("Handle Inspector"))
+	h.window.Resize(fyne.NewSize(handlesWidth, handlesHeight))
+	h.createUI()
+	h.window.CenterOnScreen()
+	h.window.Show()
+}
+
+// createUI builds the filter bar, the handle table and the export buttons
+func (h *handlesWindow) createUI() {
+	// Offer the monitored game instances, but accept any PID
+	var pids []string
+	if monitor := h.parent.monitor; monitor != nil && monitor.IsRunning() {
+		for _, inst := range monitor.Status().GameProcesses {
+			if inst.State != engine.InstanceExited {
+				pids = append(pids, strconv.FormatUint(uint64(inst.PID), 10))
+			}
+		}
+	}
+	h.pidEntry = widget.NewSelectEntry(pids)
+	h.pidEntry.SetPlaceHolder(i18n.Get("Process ID"))
+	if len(pids) > 0 {
+		h.pidEntry.SetText(pids[0])
+	}
+
+	allTypes := i18n.Get("All types")
+	h.typeSelect = widget.NewSelect(append([]string{allTypes}, handle.NamedObjectTypes...), nil)
+	h.typeSelect.SetSelected(allTypes)
+
+	h.nameEntry = widget.NewEntry()
+	h.nameEntry.SetPlaceHolder(i18n.Get("Name (regular expression)"))
+
+	h.refreshBtn = widget.NewButton(i18n.Get("Refresh"), func() {
+		h.refresh()
+	})
+	h.refreshBtn.Importance = widget.HighImportance
+
+	h.table = widget.NewTableWithHeaders(
+		func() (int, int) {
+			return len(h.handles), 4
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.TableCellID, cell fyne.CanvasObject) {
+			cell.(*widget.Label).SetText(handleCell(h.handles[id.Row], id.Col))
+		},
+	)
+	h.table.ShowHeaderColumn = false
+	h.table.UpdateHeader = func(id widget.TableCellID, cell fyne.CanvasObject) {
+		headers := []string{i18n.Get("Handle"), i18n.Get("Type"), i18n.Get("Access"), i18n.Get("Name")}
+		if id.Row < 0 && id.Col >= 0 && id.Col < len(headers) {
+			cell.(*widget.Label).SetText(headers[id.Col])
+		}
+	}
+	h.table.SetColumnWidth(0, 90)
+	h.table.SetColumnWidth(1, 110)
+	h.table.SetColumnWidth(2, 110)
+	h.table.SetColumnWidth(3, 420)
+
+	h.status = widget.NewLabel(i18n.Get("Enter a process ID and click Refresh"))
+
+	exportJSONBtn := widget.NewButton(i18n.Get("Export JSON..."), func() {
+		h.export(".json", handle.WriteJSON)
+	})
+	exportCSVBtn := widget.NewButton(i18n.Get("Export CSV..."), func() {
+		h.export(".csv", handle.WriteCSV)
+	})
+	closeBtn := widget.NewButton(i18n.Get("Close"), func() {
+		h.window.Close()
+	})
+
+	filters := container.NewBorder(nil, nil,
+		container.NewGridWrap(fyne.NewSize(120, h.pidEntry.MinSize().Height), h.pidEntry),
+		container.NewHBox(h.typeSelect, h.refreshBtn),
+		h.nameEntry,
+	)
+	buttons := container.NewBorder(nil, nil, h.status,
+		container.NewHBox(exportJSONBtn, exportCSVBtn, closeBtn),
+	)
+
+	h.window.SetContent(container.NewPadded(
+		container.NewBorder(filters, buttons, nil, nil, h.table),
+	))
+}
+
+// filter builds the handle filter from the filter bar
+func (h *handlesWindow) filter() (handle.Filter, error) {
+	var filter handle.Filter
+	if h.typeSelect.SelectedIndex() > 0 {
+		filter.TypeName = h.typeSelect.Selected
+	}
+	if pattern := strings.TrimSpace(h.nameEntry.Text); pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return filter, fmt.Errorf(i18n.Get("Invalid name pattern: %v"), err)
+		}
+		filter.NameRegex = re
+	}
+	return filter, nil
+}
+
+// refresh lists the handles of the entered process in the background
+func (h *handlesWindow) refresh() {
+	pid, err := strconv.ParseUint(strings.TrimSpace(h.pidEntry.Text), 10, 32)
+	if err != nil || pid == 0 {
+		dialog.ShowError(errors.New(i18n.Get("Enter a valid process ID")), h.window)
+		return
+	}
+	filter, err := h.filter()
+	if err != nil {
+		dialog.ShowError(err, h.window)
+		return
+	}
+
+	h.refreshBtn.Disable()
+	h.status.SetText(i18n.Get("Listing handles..."))
+
+	go func() {
+		handles, err := h.engine.InspectHandles(uint32(pid), filter)
+
+		fyne.Do(func() {
+			h.refreshBtn.Enable()
+			if err != nil {
+				h.handles = nil
+				h.status.SetText(fmt.Sprintf(i18n.Get("Failed to list handles: %v"), err))
+			} else {
+				h.handles = handles
+				h.status.SetText(fmt.Sprintf(i18n.Get("%d handle(s) in PID %d"), len(handles), pid))
+			}
+			h.table.Refresh()
+		})
+	}()
+}
+
+// export saves the listed handles with a file dialog
+func (h *handlesWindow) export(extension string, write func(io.Writer, []handle.HandleInfo) error) {
+	handles := h.handles
+	saveDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil || writer == nil {
+			return
+		}
+		if err := write(writer, handles); err != nil {
+			_ = writer.Close()
+			dialog.ShowError(err, h.window)
+			return
+		}
+		if err := writer.Close(); err != nil {
+			dialog.ShowError(err, h.window)
+			return
+		}
+		h.status.SetText(fmt.Sprintf(i18n.Get("Exported %d handle(s)"), len(handles)))
+	}, h.window)
+	saveDialog.SetFileName("handles" + extension)
+	saveDialog.SetFilter(storage.NewExtensionFileFilter([]string{extension}))
+	saveDialog.Show()
+}
+
+// handleCell returns the text of a handle table column
+func handleCell(info handle.HandleInfo, col int) string {
+	switch col {
+	case 0:
+		return fmt.Sprintf("0x%X", info.Handle)
+	case 1:
+		return info.TypeName
+	case 2:
+		return fmt.Sprintf("0x%08X", info.GrantedAccess)
+	default:
+		return info.Name
+	}
+}
@@ -0,0 +1,217 @@
+package gui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/chenwei791129/multiablo/internal/config"
+	"github.com/chenwei791129/multiablo/internal/engine"
+	"github.com/chenwei791129/multiablo/internal/i18n"
+)
+
+// createLaunchCard builds the card for launching D2R from launch profiles
+func (w *MainWindow) createLaunchCard() *widget.Card {
+	w.profileSelect = widget.NewSelect(nil, nil)
+	w.profileSelect.PlaceHolder = i18n.Get("(no launch profiles)")
+
+	w.launchBtn = widget.NewButton(i18n.Get("Launch"), func() {
+		w.onLaunchClick()
+	})
+
+	w.launchAllBtn = widget.NewButton(i18n.Get("Launch All"), func() {
+		w.onLaunchAllClick()
+	})
+
+	w.profilesBtn = widget.NewButton(i18n.Get("Profiles..."), func() {
+		w.showProfiles()
+	})
+
+	w.launchQueueList = widget.NewLabelWithData(w.launchQueueBinding)
+	w.launchQueueList.Wrapping = fyne.TextWrapWord
+
+	card := widget.NewCard(i18n.Get("Launch D2R"), "",
+		container.NewVBox(
+			container.NewBorder(nil, nil, nil,
+				container.NewHBox(w.launchBtn, w.launchAllBtn, w.profilesBtn),
+				w.profileSelect,
+			),
+			w.launchQueueList,
+		),
+	)
+	w.refreshProfiles()
+
+	return card
+}
+
+// refreshProfiles fills the launch profile selector from the settings,
+// keeping the selection when the selected profile still exists
+func (w *MainWindow) refreshProfiles() {
+	cfg := w.config()
+
+	previous := w.profileSelect.SelectedIndex()
+	var labels []string
+	for _, p := range cfg.LaunchProfiles {
+		labels = append(labels, p.Label())
+	}
+	w.profileSelect.SetOptions(labels)
+
+	switch {
+	case previous >= 0 && previous < len(labels):
+		w.profileSelect.SetSelectedIndex(previous)
+	case len(labels) > 0:
+		w.profileSelect.SetSelectedIndex(0)
+	default:
+		w.profileSelect.ClearSelected()
+	}
+
+	w.updateLaunchControls()
+}
+
+// updateLaunchControls enables the launch actions only when profiles can be launched.
+// While a queue runs, the Launch All button cancels it instead.
+func (w *MainWindow) updateLaunchControls() {
+	w.mu.Lock()
+	launching := w.isLaunching
+	w.mu.Unlock()
+
+	hasProfiles := len(w.profileSelect.Options) > 0
+
+	if launching {
+		w.launchBtn.Disable()
+		w.profileSelect.Disable()
+		w.launchAllBtn.SetText(i18n.Get("Cancel"))
+		w.launchAllBtn.Enable()
+		return
+	}
+
+	w.profileSelect.Enable()
+	w.launchAllBtn.SetText(i18n.Get("Launch All"))
+	if hasProfiles {
+		w.launchBtn.Enable()
+		w.launchAllBtn.Enable()
+	} else {
+		w.launchBtn.Disable()
+		w.launchAllBtn.Disable()
+	}
+}
+
+// onLaunchClick launches the selected profile
+func (w *MainWindow) onLaunchClick() {
+	cfg := w.config()
+	index := w.profileSelect.SelectedIndex()
+	if index < 0 || index >= len(cfg.LaunchProfiles) {
+		w.appendLog(i18n.Get("Select a launch profile first"))
+		return
+	}
+	w.startLaunchQueue(cfg.LaunchProfiles[index : index+1])
+}
+
+// onLaunchAllClick launches every profile in order, or cancels the running queue
+func (w *MainWindow) onLaunchAllClick() {
+	w.mu.Lock()
+	cancel := w.launchCancel
+	w.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		return
+	}
+	w.startLaunchQueue(w.config().LaunchProfiles)
+}
+
+// startLaunchQueue launches profiles one at a time, blocking further launches
+// until each new instance's single-instance handle has been closed
+func (w *MainWindow) startLaunchQueue(profiles []config.LaunchProfile) {
+	if len(profiles) == 0 {
+		return
+	}
+	if !w.IsMonitoring() || w.monitor == nil {
+		w.appendLog(i18n.Get("Start monitoring before launching D2R"))
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	w.mu.Lock()
+	if w.isLaunching {
+		w.mu.Unlock()
+		cancel()
+		return
+	}
+	w.isLaunching = true
+	w.launchCancel = cancel
+	w.launchQueue = make([]string, len(profiles))
+	w.mu.Unlock()
+	w.updateLaunchControls()
+
+	w.appendLog(fmt.Sprintf(i18n.Get("Launching %d profile(s)..."), len(profiles)))
+
+	go func() {
+		err := w.monitor.LaunchQueue(ctx, profiles, w.onLaunchProgress)
+		cancel()
+
+		fyne.Do(func() {
+			w.mu.Lock()
+			w.isLaunching = false
+			w.launchCancel = nil
+			w.mu.Unlock()
+			w.updateLaunchControls()
+
+			switch {
+			case err == nil:
+				w.appendLog(i18n.Get("All launches finished, the next instance can be launched"))
+			case errors.Is(err, context.Canceled):
+				w.appendLog(i18n.Get("Launch queue cancelled"))
+			case errors.Is(err, engine.ErrNotRunning):
+				w.appendLog(i18n.Get("Monitoring stopped before the launch queue finished"))
+			case errors.Is(err, engine.ErrDryRun):
+				w.appendLog(i18n.Get("Observe-only mode: nothing was launched"))
+			}
+			// Failed entries were already logged through engine events
+		})
+	}()
+}
+
+// onLaunchProgress renders a state change of a launch queue entry
+func (w *MainWindow) onLaunchProgress(p engine.LaunchProgress) {
+	line := fmt.Sprintf("%s: %s", p.Profile.Label(), launchStateText(p))
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if p.Index < 0 || p.Index >= len(w.launchQueue) {
+		return
+	}
+	w.launchQueue[p.Index] = line
+	w.launchQueueBinding.Set(strings.Join(w.launchQueue, "\n"))
+}
+
+// launchStateText describes the progress of a launch queue entry
+func launchStateText(p engine.LaunchProgress) string {
+	switch p.State {
+	case engine.LaunchPending:
+		return i18n.Get("queued")
+	case engine.LaunchStarting:
+		return fmt.Sprintf(i18n.Get("starting (attempt %d/%d)"), p.Attempt, p.MaxAttempts)
+	case engine.LaunchWaiting:
+		return fmt.Sprintf(i18n.Get("PID %d - waiting for handle to close (attempt %d/%d)"), p.PID, p.Attempt, p.MaxAttempts)
+	case engine.LaunchRetrying:
+		return fmt.Sprintf(i18n.Get("retrying (attempt %d/%d): %v"), p.Attempt, p.MaxAttempts, p.Err)
+	case engine.LaunchReady:
+		return fmt.Sprintf(i18n.Get("PID %d - ready"), p.PID)
+	case engine.LaunchFailed:
+		return fmt.Sprintf(i18n.Get("failed: %v"), p.Err)
+	case engine.LaunchCancelled:
+		if errors.Is(p.Err, engine.ErrDryRun) {
+			return i18n.Get("skipped (observe only)")
+		}
+		return i18n.Get("cancelled")
+	default:
+		return p.State.String()
+	}
+}
@@ -1,6 +1,8 @@
 package gui
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -12,28 +14,42 @@ import (
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
 
+	"github.com/chenwei791129/multiablo/internal/config"
+	"github.com/chenwei791129/multiablo/internal/engine"
+	"github.com/chenwei791129/multiablo/internal/game"
+	"github.com/chenwei791129/multiablo/internal/handle"
 	"github.com/chenwei791129/multiablo/internal/i18n"
 )
 
 const (
 	windowWidth  = 650
-	windowHeight = 550
-	maxLogLines  = 500
+	windowHeight = 800
 )
 
 // MainWindow represents the main application window
 type MainWindow struct {
+	app    fyne.App
 	window fyne.Window
 
-	// UI Components - D2R monitoring
-	d2rCountLabel         *widget.Label
-	d2rProcessList        *widget.Label
-	d2rHandlesClosedLabel *widget.Label
+	// UI Components - game monitoring
+	gameCountLabel         *widget.Label
+	gameProcessTree        *processTree
+	gameHandlesClosedLabel *widget.Label
 
 	// UI Components - Agent monitoring
 	agentCountLabel  *widget.Label
-	agentProcessList *widget.Label
+	agentProcessTree *processTree
 	agentKilledLabel *widget.Label
+	agentWarning     *widget.Label
+	agentResumeBtn   *widget.Button
+	agentWarningBox  *fyne.Container
+
+	// UI Components - Launch
+	profileSelect   *widget.Select
+	launchBtn       *widget.Button
+	launchAllBtn    *widget.Button
+	profilesBtn     *widget.Button
+	launchQueueList *widget.Label
 
 	// UI Components - Log
 	logLabel *widget.Label
@@ -41,18 +57,31 @@ type MainWindow struct {
 	// UI Components - Controls
 	startStopBtn *widget.Button
 	clearLogBtn  *widget.Button
+	settingsBtn  *widget.Button
+	handlesBtn   *widget.Button
+	dryRunCheck  *widget.Check
 
 	// Data Binding
-	d2rCountBinding     binding.String
-	d2rProcessBinding   binding.String
-	d2rHandlesBinding   binding.String
-	agentCountBinding   binding.String
-	agentProcessBinding binding.String
-	agentKilledBinding  binding.String
-	logBinding          binding.String
+	gameCountBinding   binding.String
+	gameHandlesBinding binding.String
+	agentCountBinding  binding.String
+	agentKilledBinding binding.String
+	launchQueueBinding binding.String
+	logBinding         binding.String
+
+	// Settings
+	cfg        config.Config
+	configPath string
+
+	// games holds the known game definitions
+	games *game.Registry
 
 	// State
 	isMonitoring bool
+	dryRun       bool
+	isLaunching  bool
+	launchCancel context.CancelFunc
+	launchQueue  []string
 	logLines     []string
 
 	// Monitor
@@ -62,23 +91,27 @@ type MainWindow struct {
 	mu sync.Mutex
 }
 
-// NewMainWindow creates and configures the main window
-func NewMainWindow(app fyne.App) *MainWindow {
+// NewMainWindow creates and configures the main window.
+// Settings changed in the settings window are saved to configPath.
+func NewMainWindow(app fyne.App, cfg config.Config, configPath string, games *game.Registry) *MainWindow {
 	w := &MainWindow{
+		app:          app,
+		cfg:          cfg,
+		configPath:   configPath,
+		games:        games,
 		isMonitoring: false,
-		logLines:     make([]string, 0, maxLogLines),
+		logLines:     make([]string, 0, cfg.MaxLogLines),
 	}
 	w.window = app.NewWindow(AppTitle())
 	w.window.Resize(fyne.NewSize(windowWidth, windowHeight))
 	w.window.CenterOnScreen()
 
 	// Initialize bindings
-	w.d2rCountBinding = binding.NewString()
-	w.d2rProcessBinding = binding.NewString()
-	w.d2rHandlesBinding = binding.NewString()
+	w.gameCountBinding = binding.NewString()
+	w.gameHandlesBinding = binding.NewString()
 	w.agentCountBinding = binding.NewString()
-	w.agentProcessBinding = binding.NewString()
 	w.agentKilledBinding = binding.NewString()
+	w.launchQueueBinding = binding.NewString()
 	w.logBinding = binding.NewString()
 
 	// Handle window close
@@ -99,22 +132,21 @@ func NewMainWindow(app fyne.App) *MainWindow {
 
 // createUI builds the user interface
 func (w *MainWindow) createUI() {
-	// D2R.exe monitoring section
-	w.d2rCountBinding.Set(fmt.Sprintf(i18n.Get("Detected processes: %d"), 0))
-	w.d2rCountLabel = widget.NewLabelWithData(w.d2rCountBinding)
+	// Game monitoring section
+	w.gameCountBinding.Set(fmt.Sprintf(i18n.Get("Detected processes: %d"), 0))
+	w.gameCountLabel = widget.NewLabelWithData(w.gameCountBinding)
 
-	w.d2rProcessBinding.Set(i18n.Get("No D2R.exe processes detected"))
-	w.d2rProcessList = widget.NewLabelWithData(w.d2rProcessBinding)
-	w.d2rProcessList.Wrapping = fyne.TextWrapWord
+	// Game instances under the launchers that started them
+	w.gameProcessTree = newProcessTree(i18n.Get("No game processes detected"))
 
-	w.d2rHandlesBinding.Set(fmt.Sprintf(i18n.Get("Total handles closed: %d"), 0))
-	w.d2rHandlesClosedLabel = widget.NewLabelWithData(w.d2rHandlesBinding)
+	w.gameHandlesBinding.Set(fmt.Sprintf(i18n.Get("Total handles closed: %d"), 0))
+	w.gameHandlesClosedLabel = widget.NewLabelWithData(w.gameHandlesBinding)
 
-	d2rCard := widget.NewCard(i18n.Get("D2R.exe Monitor"), "",
+	gameCard := widget.NewCard(i18n.Get("Game Monitor"), "",
 		container.NewVBox(
-			w.d2rCountLabel,
-			w.d2rProcessList,
-			w.d2rHandlesClosedLabel,
+			w.gameCountLabel,
+			w.gameProcessTree.content,
+			w.gameHandlesClosedLabel,
 		),
 	)
 
@@ -122,21 +154,34 @@ func (w *MainWindow) createUI() {
 	w.agentCountBinding.Set(fmt.Sprintf(i18n.Get("Detected processes: %d"), 0))
 	w.agentCountLabel = widget.NewLabelWithData(w.agentCountBinding)
 
-	w.agentProcessBinding.Set(i18n.Get("No Agent.exe processes detected"))
-	w.agentProcessList = widget.NewLabelWithData(w.agentProcessBinding)
-	w.agentProcessList.Wrapping = fyne.TextWrapWord
+	// Launcher helpers under the launchers that started them
+	w.agentProcessTree = newProcessTree(i18n.Get("No Agent.exe processes detected"))
 
 	w.agentKilledBinding.Set(fmt.Sprintf(i18n.Get("Total processes terminated: %d"), 0))
 	w.agentKilledLabel = widget.NewLabelWithData(w.agentKilledBinding)
 
+	// Shown while the Agent.exe handling is paused after failed relaunches
+	w.agentWarning = widget.NewLabel("")
+	w.agentWarning.Importance = widget.DangerImportance
+	w.agentWarning.Wrapping = fyne.TextWrapWord
+	w.agentResumeBtn = widget.NewButton(i18n.Get("Resume"), func() {
+		w.onResumeAgentsClick()
+	})
+	w.agentWarningBox = container.NewBorder(nil, nil, nil, w.agentResumeBtn, w.agentWarning)
+	w.agentWarningBox.Hide()
+
 	agentCard := widget.NewCard(i18n.Get("Agent.exe Monitor"), "",
 		container.NewVBox(
 			w.agentCountLabel,
-			w.agentProcessList,
+			w.agentProcessTree.content,
 			w.agentKilledLabel,
+			w.agentWarningBox,
 		),
 	)
 
+	// Launch section
+	launchCard := w.createLaunchCard()
+
 	// Log section with scroll
 	w.logLabel = widget.NewLabelWithData(w.logBinding)
 	w.logLabel.Wrapping = fyne.TextWrapWord
@@ -158,17 +203,33 @@ func (w *MainWindow) createUI() {
 		w.onClearLogClick()
 	})
 
+	w.settingsBtn = widget.NewButton(i18n.Get("Settings"), func() {
+		w.showSettings()
+	})
+
+	w.handlesBtn = widget.NewButton(i18n.Get("Handles..."), func() {
+		w.showHandles()
+	})
+
+	w.dryRunCheck = widget.NewCheck(i18n.Get("Observe only"), func(checked bool) {
+		w.onDryRunChanged(checked)
+	})
+
 	controlBox := container.NewHBox(
 		layout.NewSpacer(),
 		w.startStopBtn,
 		w.clearLogBtn,
+		w.settingsBtn,
+		w.handlesBtn,
+		w.dryRunCheck,
 		layout.NewSpacer(),
 	)
 
 	// Main layout with padding
 	content := container.NewVBox(
-		d2rCard,
+		gameCard,
 		agentCard,
+		launchCard,
 		logCard,
 		widget.NewSeparator(),
 		controlBox,
@@ -201,15 +262,26 @@ func (w *MainWindow) onStartStopClick() {
 		w.isMonitoring = true
 		w.mu.Unlock()
 
-		w.startStopBtn.SetText(i18n.Get("Stop Monitoring"))
-		w.startStopBtn.Importance = widget.DangerImportance
-		w.appendLog(i18n.Get("Monitoring started..."))
-
 		// Create and start monitor
 		if w.monitor == nil {
-			w.monitor = NewMonitor(w)
+			w.monitor = NewMonitor(w, w.config())
+			w.monitor.SetDryRun(w.IsDryRun())
+		}
+		if err := w.monitor.Start(); err != nil {
+			w.mu.Lock()
+			w.isMonitoring = false
+			w.mu.Unlock()
+			if errors.Is(err, handle.ErrArchitectureMismatch) {
+				w.appendLog(i18n.Get("Failed to start monitoring: this 32-bit build cannot manage handles on 64-bit Windows, use the 64-bit build"))
+			} else {
+				w.appendLog(fmt.Sprintf(i18n.Get("Failed to start monitoring: %v"), err))
+			}
+			return
 		}
-		w.monitor.Start()
+
+		w.startStopBtn.SetText(i18n.Get("Stop Monitoring"))
+		w.startStopBtn.Importance = widget.DangerImportance
+		w.appendLog(i18n.Get("Monitoring started..."))
 	} else {
 		w.isMonitoring = false
 		w.mu.Unlock()
@@ -225,6 +297,29 @@ func (w *MainWindow) onStartStopClick() {
 	}
 }
 
+// onDryRunChanged turns observe-only mode on or off
+func (w *MainWindow) onDryRunChanged(dryRun bool) {
+	w.mu.Lock()
+	w.dryRun = dryRun
+	w.mu.Unlock()
+
+	if w.monitor != nil {
+		w.monitor.SetDryRun(dryRun)
+	}
+	if dryRun {
+		w.appendLog(i18n.Get("Observe-only mode on: handles and processes are reported but not changed"))
+	} else {
+		w.appendLog(i18n.Get("Observe-only mode off"))
+	}
+}
+
+// IsDryRun returns whether observe-only mode is on
+func (w *MainWindow) IsDryRun() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.dryRun
+}
+
 // onClearLogClick handles the clear log button click
 func (w *MainWindow) onClearLogClick() {
 	w.mu.Lock()
@@ -243,7 +338,7 @@ func (w *MainWindow) appendLog(message string) {
 	w.logLines = append(w.logLines, logLine)
 
 	// Trim log if it exceeds max lines
-	if len(w.logLines) > maxLogLines {
+	if len(w.logLines) > w.cfg.MaxLogLines {
 		w.trimLogLocked()
 	}
 
@@ -262,34 +357,77 @@ func (w *MainWindow) trimLog() {
 
 // trimLogLocked removes older log entries (caller must hold w.mu)
 func (w *MainWindow) trimLogLocked() {
-	keepLines := maxLogLines / 2
+	keepLines := w.cfg.MaxLogLines / 2
 	if len(w.logLines) > keepLines {
 		w.logLines = w.logLines[len(w.logLines)-keepLines:]
 	}
 }
 
-// UpdateD2RStatus updates the D2R monitoring display
-func (w *MainWindow) UpdateD2RStatus(processCount int, processList string, handlesClosed int) {
-	w.d2rCountBinding.Set(fmt.Sprintf(i18n.Get("Detected processes: %d"), processCount))
-	if processList == "" {
-		w.d2rProcessBinding.Set(i18n.Get("No D2R.exe processes detected"))
-	} else {
-		w.d2rProcessBinding.Set(processList)
+// config returns the settings in effect
+func (w *MainWindow) config() config.Config {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.cfg
+}
+
+// applyConfig puts new settings into effect for the window and the running monitor
+func (w *MainWindow) applyConfig(cfg config.Config) {
+	w.mu.Lock()
+	w.cfg = cfg
+	if len(w.logLines) > w.cfg.MaxLogLines {
+		w.trimLogLocked()
+		w.logBinding.Set(strings.Join(w.logLines, "\n"))
+	}
+	w.mu.Unlock()
+
+	if w.monitor != nil {
+		w.monitor.ApplyConfig(cfg)
 	}
-	w.d2rHandlesBinding.Set(fmt.Sprintf(i18n.Get("Total handles closed: %d"), handlesClosed))
+
+	w.refreshProfiles()
+}
+
+// UpdateGameStatus updates the game monitoring display. The process tree
+// shows roots and their children with the text in labels, by PID.
+func (w *MainWindow) UpdateGameStatus(processCount int, roots []*engine.ProcessNode, labels map[uint32]string, handlesClosed int) {
+	w.gameCountBinding.Set(fmt.Sprintf(i18n.Get("Detected processes: %d"), processCount))
+	fyne.Do(func() {
+		w.gameProcessTree.set(roots, labels)
+	})
+	w.gameHandlesBinding.Set(fmt.Sprintf(i18n.Get("Total handles closed: %d"), handlesClosed))
 }
 
-// UpdateAgentStatus updates the Agent monitoring display
-func (w *MainWindow) UpdateAgentStatus(processCount int, processList string, agentsKilled int) {
+// UpdateAgentStatus updates the Agent monitoring display. The process tree
+// shows roots and their children with the text in labels, by PID.
+func (w *MainWindow) UpdateAgentStatus(processCount int, roots []*engine.ProcessNode, labels map[uint32]string, agentsKilled int) {
 	w.agentCountBinding.Set(fmt.Sprintf(i18n.Get("Detected processes: %d"), processCount))
-	if processList == "" {
-		w.agentProcessBinding.Set(i18n.Get("No Agent.exe processes detected"))
-	} else {
-		w.agentProcessBinding.Set(processList)
-	}
+	fyne.Do(func() {
+		w.agentProcessTree.set(roots, labels)
+	})
 	w.agentKilledBinding.Set(fmt.Sprintf(i18n.Get("Total processes terminated: %d"), agentsKilled))
 }
 
+// SetAgentWarning shows a warning with a Resume button in the Agent.exe
+// card, or hides it when message is empty
+func (w *MainWindow) SetAgentWarning(message string) {
+	fyne.Do(func() {
+		if message == "" {
+			w.agentWarningBox.Hide()
+			return
+		}
+		w.agentWarning.SetText(message)
+		w.agentWarningBox.Show()
+	})
+}
+
+// onResumeAgentsClick resumes the Agent.exe handling after it was paused
+func (w *MainWindow) onResumeAgentsClick() {
+	if w.monitor != nil {
+		w.monitor.ResumeAgents()
+	}
+	w.agentWarningBox.Hide()
+}
+
 // IsMonitoring returns the current monitoring state
 func (w *MainWindow) IsMonitoring() bool {
 	w.mu.Lock()
@@ -1,83 +1,73 @@
 package gui
 
 import (
+	"context"
 	"fmt"
+	"slices"
 	"sync"
 	"time"
 
-	"github.com/chenwei791129/multiablo/internal/handle"
+	"github.com/chenwei791129/multiablo/internal/config"
+	"github.com/chenwei791129/multiablo/internal/engine"
 	"github.com/chenwei791129/multiablo/internal/i18n"
-	"github.com/chenwei791129/multiablo/internal/process"
-	"github.com/chenwei791129/multiablo/pkg/d2r"
 )
 
-// ProcessInfo holds information about a monitored process
-type ProcessInfo struct {
-	PID          uint32
-	Uptime       time.Duration
-	HandleClosed bool
-}
-
-// MonitorStatus holds the current monitoring status
-type MonitorStatus struct {
-	D2RProcesses   []ProcessInfo
-	AgentProcesses []ProcessInfo
-	HandlesClosed  int
-	AgentsKilled   int
-	Event          string
-}
-
-// Monitor handles the background monitoring logic
+// Monitor connects the monitoring engine to the main window.
+// It subscribes to engine events for the activity log and periodically
+// renders engine status snapshots into the status cards.
 type Monitor struct {
-	stopChan chan struct{}
-	statusCh chan MonitorStatus
+	engine   *engine.Engine
 	window   *MainWindow
+	stopChan chan struct{}
 
-	// Statistics
-	totalHandlesClosed int
-	totalAgentsKilled  int
-	mu                 sync.Mutex
+	// uiInterval throttles status card updates
+	uiInterval time.Duration
 
 	// Running state
 	running bool
 	wg      sync.WaitGroup
+	mu      sync.Mutex
 }
 
 // NewMonitor creates a new monitor instance
-func NewMonitor(window *MainWindow) *Monitor {
+func NewMonitor(window *MainWindow, cfg config.Config) *Monitor {
 	return &Monitor{
-		window:   window,
-		statusCh: make(chan MonitorStatus, 10),
+		engine:     engine.New(engine.Options{Config: &cfg, Games: window.games}),
+		window:     window,
+		uiInterval: cfg.UIUpdateInterval.Std(),
 	}
 }
 
-// Start begins the monitoring loops
-func (m *Monitor) Start() {
+// Start begins monitoring
+func (m *Monitor) Start() error {
 	m.mu.Lock()
 	if m.running {
 		m.mu.Unlock()
-		return
+		return nil
+	}
+	m.mu.Unlock()
+
+	events, cancel := m.engine.Subscribe()
+	if err := m.engine.Start(); err != nil {
+		cancel()
+		return err
 	}
+
+	m.mu.Lock()
 	m.running = true
 	m.stopChan = make(chan struct{})
 	m.mu.Unlock()
 
-	m.wg.Add(3)
+	m.wg.Add(1)
 	go func() {
 		defer m.wg.Done()
-		m.handleCloserLoop()
-	}()
-	go func() {
-		defer m.wg.Done()
-		m.agentKillerLoop()
-	}()
-	go func() {
-		defer m.wg.Done()
-		m.statusUpdateLoop()
+		defer cancel()
+		m.statusUpdateLoop(events)
 	}()
+	return nil
 }
 
-// Stop stops the monitoring loops
+// Stop stops monitoring
 func (m *Monitor) Stop() {
 	m.mu.Lock()
 	if !m.running {
@@ -88,7 +78,9 @@ func (m *Monitor) Stop() {
 	close(m.stopChan)
 	m.mu.Unlock()
 
-	// Wait for all goroutines to finish
+	m.engine.Stop()
+
+	// Wait for the UI goroutine to finish
 	m.wg.Wait()
 }
 
@@ -99,233 +91,154 @@ func (m *Monitor) IsRunning() bool {
 	return m.running
 }
 
-// handleCloserLoop continuously monitors D2R processes and closes their single-instance handles
-func (m *Monitor) handleCloserLoop() {
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-m.stopChan:
-			return
-		case <-ticker.C:
-			m.checkD2RProcesses()
-		}
-	}
-}
-
-// checkD2RProcesses finds D2R processes and closes their single-instance handles
-func (m *Monitor) checkD2RProcesses() {
-	processes, err := process.FindProcessesByName(d2r.ProcessName)
-	if err != nil {
-		return
-	}
-
-	var d2rInfos []ProcessInfo
-	for _, proc := range processes {
-		info := ProcessInfo{
-			PID:          proc.PID,
-			HandleClosed: false,
-		}
-
-		// Try to close handles
-		closedCount, err := handle.CloseHandlesByName(proc.PID, d2r.SingleInstanceEventName)
-		if err == nil && closedCount > 0 {
-			info.HandleClosed = true
-			m.mu.Lock()
-			m.totalHandlesClosed += closedCount
-			m.mu.Unlock()
-
-			m.sendStatus(MonitorStatus{
-				Event: fmt.Sprintf(i18n.Get("Closed %d handle(s) for D2R.exe (PID: %d)"), closedCount, proc.PID),
-			})
-		}
-
-		d2rInfos = append(d2rInfos, info)
-	}
-
-	// Send status update
+// ApplyConfig applies new settings to the engine and the UI throttle
+// without restarting monitoring
+func (m *Monitor) ApplyConfig(cfg config.Config) {
 	m.mu.Lock()
-	totalClosed := m.totalHandlesClosed
+	m.uiInterval = cfg.UIUpdateInterval.Std()
 	m.mu.Unlock()
 
-	m.sendStatus(MonitorStatus{
-		D2RProcesses:  d2rInfos,
-		HandlesClosed: totalClosed,
-	})
+	m.engine.SetConfig(cfg)
 }
 
-// agentKillerLoop continuously monitors and kills Agent.exe processes
-func (m *Monitor) agentKillerLoop() {
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-m.stopChan:
-			return
-		case <-ticker.C:
-			m.checkAgentProcesses()
-		}
-	}
+// SetDryRun turns observe-only mode of the engine on or off
+func (m *Monitor) SetDryRun(dryRun bool) {
+	m.engine.SetDryRun(dryRun)
 }
 
-// checkAgentProcesses finds Agent.exe processes and kills them if needed
-func (m *Monitor) checkAgentProcesses() {
-	processes, err := process.FindProcessesByName(d2r.AgentProcessName)
-	if err != nil {
-		return
-	}
-
-	var agentInfos []ProcessInfo
-	for _, proc := range processes {
-		uptime, _ := process.GetProcessUptime(proc.PID)
-		info := ProcessInfo{
-			PID:    proc.PID,
-			Uptime: uptime,
-		}
-		agentInfos = append(agentInfos, info)
-	}
-
-	// Check if we should kill Agent.exe
-	if len(processes) > 0 {
-		uptime, _ := process.GetProcessOldestUptimeByName(d2r.AgentProcessName)
-		if uptime >= (time.Second * 7) {
-			// Get Agent.exe path before killing
-			agentPath := ""
-			path, err := process.GetProcessExecutablePath(processes[0].PID)
-			if err == nil {
-				agentPath = path
-			}
-			if agentPath == "" {
-				agentPath = d2r.DefaultAgentPath
-			}
-
-			// Kill Agent.exe processes
-			killedCount, err := process.KillProcessesByName(d2r.AgentProcessName)
-			if err == nil && killedCount > 0 {
-				m.mu.Lock()
-				m.totalAgentsKilled += killedCount
-				m.mu.Unlock()
-
-				m.sendStatus(MonitorStatus{
-					Event: fmt.Sprintf(i18n.Get("Terminated %d Agent.exe process(es)"), killedCount),
-				})
-
-				// Relaunch Agent.exe
-				err = process.LaunchProcess(agentPath)
-				if err != nil {
-					m.sendStatus(MonitorStatus{
-						Event: fmt.Sprintf(i18n.Get("Failed to relaunch Agent.exe: %v"), err),
-					})
-				} else {
-					m.sendStatus(MonitorStatus{
-						Event: i18n.Get("Relaunched Agent.exe successfully"),
-					})
-				}
-			}
-		}
-	}
-
-	// Send status update
-	m.mu.Lock()
-	totalKilled := m.totalAgentsKilled
-	m.mu.Unlock()
+// ResumeAgents resumes the Agent.exe handling of the engine after it was paused
+func (m *Monitor) ResumeAgents() {
+	m.engine.ResumeAgents()
+}
 
-	m.sendStatus(MonitorStatus{
-		AgentProcesses: agentInfos,
-		AgentsKilled:   totalKilled,
-	})
+// Status returns the current engine status
+func (m *Monitor) Status() engine.Status {
+	return m.engine.Status()
 }
 
-// sendStatus sends a status update to the channel
-func (m *Monitor) sendStatus(status MonitorStatus) {
-	select {
-	case m.statusCh <- status:
-	default:
-		// Channel full, skip this update
-	}
+// LaunchQueue starts launch profiles one at a time, waiting for each new
+// instance's single-instance handle to be closed before the next start
+func (m *Monitor) LaunchQueue(ctx context.Context, profiles []config.LaunchProfile, progress func(engine.LaunchProgress)) error {
+	return m.engine.LaunchQueue(ctx, profiles, progress)
 }
 
-// statusUpdateLoop processes status updates and updates the UI
-func (m *Monitor) statusUpdateLoop() {
-	var lastD2RProcesses []ProcessInfo
-	var lastAgentProcesses []ProcessInfo
-	var lastHandlesClosed int
-	var lastAgentsKilled int
+// uiUpdateInterval returns the current UI throttle interval
+func (m *Monitor) uiUpdateInterval() time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.uiInterval
+}
 
+// statusUpdateLoop logs engine events and updates the UI with engine status
+func (m *Monitor) statusUpdateLoop(events <-chan engine.Event) {
 	// Use a ticker to throttle UI updates
-	updateTicker := time.NewTicker(500 * time.Millisecond)
+	interval := m.uiUpdateInterval()
+	updateTicker := time.NewTicker(interval)
 	defer updateTicker.Stop()
 
-	needsUpdate := false
-
 	for {
 		select {
 		case <-m.stopChan:
 			return
-		case status := <-m.statusCh:
-			// Update D2R status if changed
-			if status.D2RProcesses != nil {
-				lastD2RProcesses = status.D2RProcesses
-				needsUpdate = true
-			}
-			if status.HandlesClosed > 0 {
-				lastHandlesClosed = status.HandlesClosed
-				needsUpdate = true
-			}
-
-			// Update Agent status if changed
-			if status.AgentProcesses != nil {
-				lastAgentProcesses = status.AgentProcesses
-				needsUpdate = true
-			}
-			if status.AgentsKilled > 0 {
-				lastAgentsKilled = status.AgentsKilled
-				needsUpdate = true
-			}
-
+		case event := <-events:
 			// Log events immediately
-			if status.Event != "" {
-				m.window.AppendLog(status.Event)
+			if event.Message != "" {
+				m.window.AppendLog(event.Message)
 			}
 		case <-updateTicker.C:
 			// Throttled UI update
-			if needsUpdate {
-				m.updateD2RUI(lastD2RProcesses, lastHandlesClosed)
-				m.updateAgentUI(lastAgentProcesses, lastAgentsKilled)
-				needsUpdate = false
+			status := m.engine.Status()
+			m.updateGameUI(status)
+			m.updateAgentUI(status)
+			m.updateAgentWarning(status)
+
+			// Follow interval changes made through ApplyConfig
+			if next := m.uiUpdateInterval(); next != interval {
+				interval = next
+				updateTicker.Reset(interval)
 			}
 		}
 	}
 }
 
-// updateD2RUI updates the D2R section of the UI
-func (m *Monitor) updateD2RUI(processes []ProcessInfo, handlesClosed int) {
-	processList := ""
-	for _, p := range processes {
-		status := i18n.Get("monitoring")
-		if p.HandleClosed {
-			status = i18n.Get("handle closed")
-		}
-		if processList != "" {
-			processList += "\n"
+// updateGameUI updates the game section of the UI with the running game
+// instances under the launchers that started them
+func (m *Monitor) updateGameUI(status engine.Status) {
+	labels := launcherLabels(status)
+	running := 0
+	for _, inst := range status.GameProcesses {
+		if inst.State != engine.InstanceExited {
+			running++
+			labels[inst.PID] = fmt.Sprintf(i18n.Get("%s PID %d - %s"), inst.ProcessName, inst.PID, instanceStateText(inst))
 		}
-		processList += fmt.Sprintf(i18n.Get("PID %d - %s"), p.PID, status)
 	}
 
-	m.window.UpdateD2RStatus(len(processes), processList, handlesClosed)
+	roots := processRoots(status.ProcessNodes(engine.ProcessLauncher, engine.ProcessGame))
+	m.window.UpdateGameStatus(running, roots, labels, status.HandlesClosed)
 }
 
-// updateAgentUI updates the Agent section of the UI
-func (m *Monitor) updateAgentUI(processes []ProcessInfo, agentsKilled int) {
-	processList := ""
-	for _, p := range processes {
-		if processList != "" {
-			processList += "\n"
+// instanceStateText describes the state of a game instance
+func instanceStateText(inst engine.InstanceStatus) string {
+	switch inst.State {
+	case engine.InstanceDetected:
+		return i18n.Get("detected")
+	case engine.InstanceScanning:
+		return i18n.Get("scanning")
+	case engine.InstanceHandleClosed:
+		return i18n.Get("handle closed")
+	case engine.InstanceVerified:
+		return i18n.Get("handle closed (verified)")
+	case engine.InstanceRetrying:
+		if inst.Err != nil {
+			return fmt.Sprintf(i18n.Get("retrying (attempt %d): %v"), inst.Attempts, inst.Err)
 		}
-		processList += fmt.Sprintf(i18n.Get("PID %d - uptime: %.1fs"), p.PID, p.Uptime.Seconds())
+		return i18n.Get("waiting for handle")
+	case engine.InstanceFailed:
+		return fmt.Sprintf(i18n.Get("failed: %v"), inst.Err)
+	case engine.InstanceExited:
+		return i18n.Get("exited")
+	case engine.InstanceObserved:
+		return i18n.Get("handle found, left open (observe only)")
+	default:
+		return inst.State.String()
+	}
+}
+
+// updateAgentUI updates the Agent section of the UI with the launcher
+// helpers under the launchers that started them
+func (m *Monitor) updateAgentUI(status engine.Status) {
+	labels := launcherLabels(status)
+	for _, p := range status.AgentProcesses {
+		labels[p.PID] = fmt.Sprintf(i18n.Get("%s PID %d - uptime: %.1fs"), p.Name, p.PID, p.Uptime.Seconds())
+	}
+
+	roots := processRoots(status.ProcessNodes(engine.ProcessLauncher, engine.ProcessHelper))
+	m.window.UpdateAgentStatus(len(status.AgentProcesses), roots, labels, status.AgentsKilled)
+}
+
+// launcherLabels returns the tree labels of the launchers, by PID
+func launcherLabels(status engine.Status) map[uint32]string {
+	labels := make(map[uint32]string)
+	for _, p := range status.Launchers {
+		labels[p.PID] = fmt.Sprintf(i18n.Get("%s PID %d"), p.Name, p.PID)
 	}
+	return labels
+}
 
-	m.window.UpdateAgentStatus(len(processes), processList, agentsKilled)
+// processRoots builds the process tree, leaving out launchers that started
+// none of the processes
+func processRoots(nodes []engine.ProcessNode) []*engine.ProcessNode {
+	roots := engine.BuildProcessTree(nodes)
+	return slices.DeleteFunc(roots, func(node *engine.ProcessNode) bool {
+		return node.Kind == engine.ProcessLauncher && len(node.Children) == 0
+	})
+}
+
+// updateAgentWarning shows which launcher helper's handling is paused and why, if it is
+func (m *Monitor) updateAgentWarning(status engine.Status) {
+	if !status.AgentsPaused {
+		m.window.SetAgentWarning("")
+		return
+	}
+	m.window.SetAgentWarning(fmt.Sprintf(i18n.Get("The handling of %s is paused: %v"), status.AgentPauseHelper, status.AgentPauseErr))
 }
@@ -0,0 +1,126 @@
+package gui
+
+import (
+	"image/color"
+	"strconv"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/chenwei791129/multiablo/internal/engine"
+)
+
+// processTreeHeight is the height of a process tree, about four rows
+const processTreeHeight = 120
+
+// processTree shows a process tree with one label per process, and a
+// placeholder while it is empty. It must only be used on the Fyne thread.
+type processTree struct {
+	tree        *widget.Tree
+	placeholder *widget.Label
+	content     *fyne.Container
+
+	roots  []*engine.ProcessNode
+	nodes  map[widget.TreeNodeID]*engine.ProcessNode
+	labels map[uint32]string
+
+	// opened holds the branches opened so far; new branches are opened
+	// once, so that a branch the user closed stays closed
+	opened map[widget.TreeNodeID]bool
+}
+
+// newProcessTree creates an empty process tree showing placeholder
+func newProcessTree(placeholder string) *processTree {
+	t := &processTree{
+		nodes:  make(map[widget.TreeNodeID]*engine.ProcessNode),
+		opened: make(map[widget.TreeNodeID]bool),
+	}
+
+	t.tree = widget.NewTree(
+		func(id widget.TreeNodeID) []widget.TreeNodeID {
+			children := t.roots
+			if id != "" {
+				node, ok := t.nodes[id]
+				if !ok {
+					return nil
+				}
+				children = node.Children
+			}
+			ids := make([]widget.TreeNodeID, 0, len(children))
+			for _, child := range children {
+				ids = append(ids, nodeID(child))
+			}
+			return ids
+		},
+		func(id widget.TreeNodeID) bool {
+			if id == "" {
+				return true
+			}
+			node, ok := t.nodes[id]
+			return ok && len(node.Children) > 0
+		},
+		func(bool) fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.TreeNodeID, _ bool, cell fyne.CanvasObject) {
+			if node, ok := t.nodes[id]; ok {
+				cell.(*widget.Label).SetText(t.labels[node.PID])
+			}
+		},
+	)
+
+	t.placeholder = widget.NewLabel(placeholder)
+
+	// The tree scrolls, so it needs a fixed height inside the card
+	sizer := canvas.NewRectangle(color.Transparent)
+	sizer.SetMinSize(fyne.NewSize(0, processTreeHeight))
+	t.content = container.NewStack(sizer, t.tree, t.placeholder)
+	return t
+}
+
+// set replaces the shown processes; labels holds the text of each process by PID
+func (t *processTree) set(roots []*engine.ProcessNode, labels map[uint32]string) {
+	t.roots = roots
+	t.labels = labels
+	clear(t.nodes)
+
+	var branches []widget.TreeNodeID
+	var walk func(nodes []*engine.ProcessNode)
+	walk = func(nodes []*engine.ProcessNode) {
+		for _, node := range nodes {
+			id := nodeID(node)
+			t.nodes[id] = node
+			if len(node.Children) > 0 {
+				branches = append(branches, id)
+			}
+			walk(node.Children)
+		}
+	}
+	walk(roots)
+
+	t.tree.Refresh()
+	for _, id := range branches {
+		if !t.opened[id] {
+			t.opened[id] = true
+			t.tree.OpenBranch(id)
+		}
+	}
+	for id := range t.opened {
+		if _, ok := t.nodes[id]; !ok {
+			delete(t.opened, id)
+		}
+	}
+
+	if len(roots) == 0 {
+		t.placeholder.Show()
+	} else {
+		t.placeholder.Hide()
+	}
+}
+
+// nodeID returns the tree node ID of a process
+func nodeID(node *engine.ProcessNode) widget.TreeNodeID {
+	return strconv.FormatUint(uint64(node.PID), 10)
+}
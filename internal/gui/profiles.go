@@ -0,0 +1,335 @@
+package gui
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/chenwei791129/multiablo/internal/config"
+	"github.com/chenwei791129/multiablo/internal/i18n"
+	"github.com/chenwei791129/multiablo/pkg/d2r"
+)
+
+const (
+	profilesWidth  = 680
+	profilesHeight = 400
+)
+
+// profilesWindow lets the user add, edit and remove launch profiles
+type profilesWindow struct {
+	parent *MainWindow
+	window fyne.Window
+
+	// profiles is the working copy edited in this window
+	profiles []config.LaunchProfile
+	// current is the index of the profile shown in the form, or -1
+	current int
+
+	list             *widget.List
+	deleteBtn        *widget.Button
+	nameEntry        *widget.Entry
+	displayNameEntry *widget.Entry
+	pathEntry        *widget.Entry
+	argsEntry        *widget.Entry
+	workDirEntry     *widget.Entry
+	envEntry         *widget.Entry
+	pathBrowseBtn    *widget.Button
+	workDirBrowseBtn *widget.Button
+}
+
+// showProfiles opens the launch profiles window
+func (w *MainWindow) showProfiles() {
+	p := &profilesWindow{
+		parent:   w,
+		profiles: append([]config.LaunchProfile(nil), w.config().LaunchProfiles...),
+		current:  -1,
+	}
+	p.window = w.app.NewWindow(i18n.Get("Launch Profiles"))
+	p.window.Resize(fyne.NewSize(profilesWidth, profilesHeight))
+	p.createUI()
+	p.window.CenterOnScreen()
+	p.window.Show()
+}
+
+// createUI builds the profile list and the profile form
+func (p *profilesWindow) createUI() {
+	p.list = widget.NewList(
+		func() int {
+			return len(p.profiles)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			item.(*widget.Label).SetText(p.profiles[id].Label())
+		},
+	)
+	p.list.OnSelected = func(id widget.ListItemID) {
+		p.storeCurrent()
+		p.load(id)
+	}
+
+	addBtn := widget.NewButton(i18n.Get("Add"), func() {
+		p.onAdd()
+	})
+	p.deleteBtn = widget.NewButton(i18n.Get("Delete"), func() {
+		p.onDelete()
+	})
+
+	p.nameEntry = widget.NewEntry()
+	p.displayNameEntry = widget.NewEntry()
+	p.pathEntry = widget.NewEntry()
+	p.argsEntry = widget.NewEntry()
+	p.argsEntry.SetPlaceHolder("-mod mymod -txt")
+	p.workDirEntry = widget.NewEntry()
+	p.workDirEntry.SetPlaceHolder(i18n.Get("(folder of D2R.exe)"))
+	p.envEntry = widget.NewMultiLineEntry()
+	p.envEntry.SetPlaceHolder(i18n.Get("KEY=value, one per line"))
+	p.envEntry.SetMinRowsVisible(3)
+
+	p.pathBrowseBtn = widget.NewButton(i18n.Get("Browse..."), func() {
+		p.browsePath()
+	})
+	p.workDirBrowseBtn = widget.NewButton(i18n.Get("Browse..."), func() {
+		p.browseWorkDir()
+	})
+
+	form := widget.NewForm(
+		widget.NewFormItem(i18n.Get("Name"), p.nameEntry),
+		widget.NewFormItem(i18n.Get("Display name"), p.displayNameEntry),
+		widget.NewFormItem(i18n.Get("D2R.exe path"), container.NewBorder(nil, nil, nil, p.pathBrowseBtn, p.pathEntry)),
+		widget.NewFormItem(i18n.Get("Arguments"), p.argsEntry),
+		widget.NewFormItem(i18n.Get("Working directory"), container.NewBorder(nil, nil, nil, p.workDirBrowseBtn, p.workDirEntry)),
+		widget.NewFormItem(i18n.Get("Environment"), p.envEntry),
+	)
+
+	hint := widget.NewLabel(i18n.Get("Arguments are separated by spaces; use double quotes around values that contain spaces."))
+	hint.Wrapping = fyne.TextWrapWord
+
+	saveBtn := widget.NewButton(i18n.Get("Save"), func() {
+		p.onSave()
+	})
+	saveBtn.Importance = widget.HighImportance
+	cancelBtn := widget.NewButton(i18n.Get("Cancel"), func() {
+		p.window.Close()
+	})
+
+	listPane := container.NewBorder(nil, container.NewGridWithColumns(2, addBtn, p.deleteBtn), nil, nil, p.list)
+	formPane := container.NewVBox(form, hint)
+	split := container.NewHSplit(listPane, formPane)
+	split.Offset = 0.3
+
+	p.window.SetContent(container.NewPadded(
+		container.NewBorder(nil, container.NewHBox(layout.NewSpacer(), cancelBtn, saveBtn), nil, nil, split),
+	))
+
+	if len(p.profiles) > 0 {
+		p.list.Select(0)
+	} else {
+		p.load(-1)
+	}
+}
+
+// load shows the profile at index in the form; -1 clears and disables the form
+func (p *profilesWindow) load(index int) {
+	p.current = index
+
+	var profile config.LaunchProfile
+	if index >= 0 && index < len(p.profiles) {
+		profile = p.profiles[index]
+	}
+	p.nameEntry.SetText(profile.Name)
+	p.displayNameEntry.SetText(profile.DisplayName)
+	p.pathEntry.SetText(profile.Path)
+	p.argsEntry.SetText(joinArgs(profile.Args))
+	p.workDirEntry.SetText(profile.WorkDir)
+	p.envEntry.SetText(strings.Join(profile.Env, "\n"))
+
+	editable := []fyne.Disableable{
+		p.deleteBtn, p.nameEntry, p.displayNameEntry, p.pathEntry,
+		p.argsEntry, p.workDirEntry, p.envEntry, p.pathBrowseBtn, p.workDirBrowseBtn,
+	}
+	for _, item := range editable {
+		if p.current < 0 {
+			item.Disable()
+		} else {
+			item.Enable()
+		}
+	}
+}
+
+// storeCurrent copies the form into the profile being edited
+func (p *profilesWindow) storeCurrent() {
+	if p.current < 0 || p.current >= len(p.profiles) {
+		return
+	}
+
+	p.profiles[p.current] = config.LaunchProfile{
+		Name:        strings.TrimSpace(p.nameEntry.Text),
+		DisplayName: strings.TrimSpace(p.displayNameEntry.Text),
+		Path:        strings.TrimSpace(p.pathEntry.Text),
+		Args:        splitArgs(p.argsEntry.Text),
+		WorkDir:     strings.TrimSpace(p.workDirEntry.Text),
+		Env:         splitEnv(p.envEntry.Text),
+	}
+	p.list.RefreshItem(p.current)
+}
+
+// onAdd appends a new profile with default values and selects it
+func (p *profilesWindow) onAdd() {
+	p.storeCurrent()
+
+	p.profiles = append(p.profiles, config.LaunchProfile{
+		Name: p.unusedName(),
+		Path: d2r.DefaultGamePath,
+	})
+	p.list.Refresh()
+	p.list.Select(len(p.profiles) - 1)
+}
+
+// unusedName returns a profile name that is not taken yet
+func (p *profilesWindow) unusedName() string {
+	for n := len(p.profiles) + 1; ; n++ {
+		name := fmt.Sprintf(i18n.Get("Account %d"), n)
+		taken := false
+		for _, profile := range p.profiles {
+			if profile.Name == name {
+				taken = true
+				break
+			}
+		}
+		if !taken {
+			return name
+		}
+	}
+}
+
+// onDelete removes the profile being edited
+func (p *profilesWindow) onDelete() {
+	if p.current < 0 || p.current >= len(p.profiles) {
+		return
+	}
+
+	p.profiles = append(p.profiles[:p.current], p.profiles[p.current+1:]...)
+	next := min(p.current, len(p.profiles)-1)
+
+	// Forget the deleted profile so selecting another one does not store it back
+	p.current = -1
+	p.list.UnselectAll()
+	p.list.Refresh()
+	if next >= 0 {
+		p.list.Select(next)
+	} else {
+		p.load(-1)
+	}
+}
+
+// browsePath lets the user pick D2R.exe with a file dialog
+func (p *profilesWindow) browsePath() {
+	fileDialog := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil || reader == nil {
+			return
+		}
+		defer func() {
+			_ = reader.Close()
+		}()
+		p.pathEntry.SetText(filepath.FromSlash(reader.URI().Path()))
+	}, p.window)
+	fileDialog.SetFilter(storage.NewExtensionFileFilter([]string{".exe"}))
+	fileDialog.Show()
+}
+
+// browseWorkDir lets the user pick the working directory with a folder dialog
+func (p *profilesWindow) browseWorkDir() {
+	dialog.ShowFolderOpen(func(dir fyne.ListableURI, err error) {
+		if err != nil || dir == nil {
+			return
+		}
+		p.workDirEntry.SetText(filepath.FromSlash(dir.Path()))
+	}, p.window)
+}
+
+// onSave validates, saves and applies the edited profiles
+func (p *profilesWindow) onSave() {
+	p.storeCurrent()
+
+	cfg := p.parent.config()
+	cfg.LaunchProfiles = append([]config.LaunchProfile{}, p.profiles...)
+	if err := cfg.Validate(); err != nil {
+		dialog.ShowError(err, p.window)
+		return
+	}
+
+	if p.parent.configPath == "" {
+		dialog.ShowError(errors.New(i18n.Get("No location is available to save settings")), p.window)
+		return
+	}
+	if err := config.Save(p.parent.configPath, cfg); err != nil {
+		dialog.ShowError(err, p.window)
+		return
+	}
+
+	p.parent.applyConfig(cfg)
+	p.parent.appendLog(i18n.Get("Launch profiles saved"))
+	p.window.Close()
+}
+
+// splitArgs splits a command line into arguments at spaces,
+// keeping double-quoted text together
+func splitArgs(text string) []string {
+	args := []string{}
+	var current strings.Builder
+	inQuotes, hasArg := false, false
+
+	for _, r := range text {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			hasArg = true
+		case (r == ' ' || r == '\t') && !inQuotes:
+			if hasArg {
+				args = append(args, current.String())
+				current.Reset()
+				hasArg = false
+			}
+		default:
+			current.WriteRune(r)
+			hasArg = true
+		}
+	}
+	if hasArg {
+		args = append(args, current.String())
+	}
+	return args
+}
+
+// splitEnv returns the non-empty lines of text as "KEY=value" variables
+func splitEnv(text string) []string {
+	var env []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			env = append(env, line)
+		}
+	}
+	return env
+}
+
+// joinArgs is the inverse of splitArgs, quoting arguments that contain spaces
+func joinArgs(args []string) string {
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t") {
+			arg = `"` + arg + `"`
+		}
+		quoted = append(quoted, arg)
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,319 @@
+package gui
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/chenwei791129/multiablo/internal/config"
+	"github.com/chenwei791129/multiablo/internal/i18n"
+)
+
+const (
+	settingsWidth  = 520
+	settingsHeight = 600
+)
+
+// languageOption pairs a language code with its display name
+type languageOption struct {
+	code string
+	name string
+}
+
+// languageOptions returns the selectable UI languages, starting with the system default
+func languageOptions() []languageOption {
+	return []languageOption{
+		{code: "", name: i18n.Get("System default")},
+		{code: i18n.LangEnUS, name: "English"},
+		{code: i18n.LangZhTW, name: "繁體中文"},
+	}
+}
+
+// strategyOption pairs an agent_strategy value with its description
+type strategyOption struct {
+	name  string
+	label string
+}
+
+// strategyOptions returns the selectable Agent.exe strategies
+func strategyOptions() []strategyOption {
+	return []strategyOption{
+		{name: config.StrategyKillRelaunch, label: i18n.Get("Terminate after the threshold and relaunch")},
+		{name: config.StrategyKillOnly, label: i18n.Get("Terminate after the threshold without relaunching")},
+		{name: config.StrategyLeaveAlone, label: i18n.Get("Leave alone")},
+		{name: config.StrategyDuringLaunch, label: i18n.Get("Terminate and relaunch only while launching D2R")},
+		{name: config.StrategyMinInstances, label: i18n.Get("Terminate and relaunch only when enough game instances run")},
+	}
+}
+
+// strategyName returns the agent_strategy value of a strategy description
+func strategyName(label string) string {
+	for _, opt := range strategyOptions() {
+		if opt.label == label {
+			return opt.name
+		}
+	}
+	return ""
+}
+
+// settingsWindow lets the user edit, validate and save the settings
+type settingsWindow struct {
+	parent *MainWindow
+	window fyne.Window
+
+	handleIntervalEntry *widget.Entry
+	agentIntervalEntry  *widget.Entry
+	uiIntervalEntry     *widget.Entry
+	agentStrategySelect *widget.Select
+	agentInstancesEntry *widget.Entry
+	agentThresholdEntry *widget.Entry
+	agentCrashEntry     *widget.Entry
+	agentPathEntry      *widget.Entry
+	maxLogLinesEntry    *widget.Entry
+	launchTimeoutEntry  *widget.Entry
+	launchRetriesEntry  *widget.Entry
+	languageSelect      *widget.Select
+	gamesCheck          *widget.CheckGroup
+}
+
+// showSettings opens the settings window
+func (w *MainWindow) showSettings() {
+	s := &settingsWindow{parent: w}
+	s.window = w.app.NewWindow(i18n.Get("Settings"))
+	s.window.Resize(fyne.NewSize(settingsWidth, settingsHeight))
+	s.createUI(w.config())
+	s.window.CenterOnScreen()
+	s.window.Show()
+}
+
+// createUI builds the settings form pre-filled with cfg
+func (s *settingsWindow) createUI(cfg config.Config) {
+	s.handleIntervalEntry = newDurationEntry(cfg.HandleCheckInterval)
+	s.agentIntervalEntry = newDurationEntry(cfg.AgentCheckInterval)
+	s.uiIntervalEntry = newDurationEntry(cfg.UIUpdateInterval)
+	s.agentThresholdEntry = newDurationEntry(cfg.AgentKillThreshold)
+	s.agentCrashEntry = newDurationEntry(cfg.AgentCrashWindow)
+	s.launchTimeoutEntry = newDurationEntry(cfg.LaunchTimeout)
+
+	var strategyLabels []string
+	selectedStrategy := ""
+	for _, opt := range strategyOptions() {
+		strategyLabels = append(strategyLabels, opt.label)
+		if opt.name == cfg.AgentStrategy {
+			selectedStrategy = opt.label
+		}
+	}
+	s.agentInstancesEntry = newIntEntry(cfg.AgentMinInstances)
+	s.agentStrategySelect = widget.NewSelect(strategyLabels, func(label string) {
+		// The instance count only applies to its own strategy
+		if strategyName(label) == config.StrategyMinInstances {
+			s.agentInstancesEntry.Enable()
+		} else {
+			s.agentInstancesEntry.Disable()
+		}
+	})
+	s.agentStrategySelect.SetSelected(selectedStrategy)
+
+	s.agentPathEntry = widget.NewEntry()
+	s.agentPathEntry.SetText(cfg.AgentPath)
+	browseBtn := widget.NewButton(i18n.Get("Browse..."), func() {
+		s.browseAgentPath()
+	})
+
+	s.maxLogLinesEntry = newIntEntry(cfg.MaxLogLines)
+	s.launchRetriesEntry = newIntEntry(cfg.LaunchRetries)
+
+	var languageNames []string
+	selected := ""
+	for _, opt := range languageOptions() {
+		languageNames = append(languageNames, opt.name)
+		if opt.code == cfg.Language {
+			selected = opt.name
+		}
+	}
+	s.languageSelect = widget.NewSelect(languageNames, nil)
+	s.languageSelect.SetSelected(selected)
+
+	var gameLabels, enabledLabels []string
+	for _, d := range s.parent.games.Definitions() {
+		gameLabels = append(gameLabels, d.Label())
+		if slices.Contains(cfg.EnabledGames, d.ID) {
+			enabledLabels = append(enabledLabels, d.Label())
+		}
+	}
+	s.gamesCheck = widget.NewCheckGroup(gameLabels, nil)
+	s.gamesCheck.SetSelected(enabledLabels)
+
+	form := widget.NewForm(
+		widget.NewFormItem(i18n.Get("Games"), s.gamesCheck),
+		widget.NewFormItem(i18n.Get("Game process check interval"), s.handleIntervalEntry),
+		widget.NewFormItem(i18n.Get("Agent.exe check interval"), s.agentIntervalEntry),
+		widget.NewFormItem(i18n.Get("UI refresh interval"), s.uiIntervalEntry),
+		widget.NewFormItem(i18n.Get("Agent.exe handling"), s.agentStrategySelect),
+		widget.NewFormItem(i18n.Get("Minimum game instances"), s.agentInstancesEntry),
+		widget.NewFormItem(i18n.Get("Agent.exe kill threshold"), s.agentThresholdEntry),
+		widget.NewFormItem(i18n.Get("Agent.exe crash window"), s.agentCrashEntry),
+		widget.NewFormItem(i18n.Get("Agent.exe path"), container.NewBorder(nil, nil, nil, browseBtn, s.agentPathEntry)),
+		widget.NewFormItem(i18n.Get("Max log lines"), s.maxLogLinesEntry),
+		widget.NewFormItem(i18n.Get("D2R launch timeout"), s.launchTimeoutEntry),
+		widget.NewFormItem(i18n.Get("D2R launch retries"), s.launchRetriesEntry),
+		widget.NewFormItem(i18n.Get("Language"), s.languageSelect),
+	)
+	form.SubmitText = i18n.Get("Save")
+	form.CancelText = i18n.Get("Cancel")
+	form.OnSubmit = func() {
+		s.onSave()
+	}
+	form.OnCancel = func() {
+		s.window.Close()
+	}
+
+	hint := widget.NewLabel(i18n.Get("Durations use units such as 500ms, 2s or 1m."))
+	hint.Wrapping = fyne.TextWrapWord
+
+	s.window.SetContent(container.NewPadded(container.NewVBox(form, hint)))
+}
+
+// newDurationEntry creates an entry for a duration setting
+func newDurationEntry(d config.Duration) *widget.Entry {
+	entry := widget.NewEntry()
+	entry.SetText(d.String())
+	entry.Validator = func(text string) error {
+		if _, err := time.ParseDuration(strings.TrimSpace(text)); err != nil {
+			return errors.New(i18n.Get("Enter a duration such as 500ms or 2s"))
+		}
+		return nil
+	}
+	return entry
+}
+
+// newIntEntry creates an entry for a whole number setting
+func newIntEntry(n int) *widget.Entry {
+	entry := widget.NewEntry()
+	entry.SetText(strconv.Itoa(n))
+	entry.Validator = func(text string) error {
+		if _, err := strconv.Atoi(strings.TrimSpace(text)); err != nil {
+			return errors.New(i18n.Get("Enter a whole number"))
+		}
+		return nil
+	}
+	return entry
+}
+
+// browseAgentPath lets the user pick Agent.exe with a file dialog
+func (s *settingsWindow) browseAgentPath() {
+	fileDialog := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil || reader == nil {
+			return
+		}
+		defer func() {
+			_ = reader.Close()
+		}()
+		s.agentPathEntry.SetText(filepath.FromSlash(reader.URI().Path()))
+	}, s.window)
+	fileDialog.SetFilter(storage.NewExtensionFileFilter([]string{".exe"}))
+	fileDialog.Show()
+}
+
+// readConfig builds new settings from the form, keeping settings the form does not show
+func (s *settingsWindow) readConfig() (config.Config, error) {
+	cfg := s.parent.config()
+
+	durations := []struct {
+		entry *widget.Entry
+		dest  *config.Duration
+	}{
+		{s.handleIntervalEntry, &cfg.HandleCheckInterval},
+		{s.agentIntervalEntry, &cfg.AgentCheckInterval},
+		{s.uiIntervalEntry, &cfg.UIUpdateInterval},
+		{s.agentThresholdEntry, &cfg.AgentKillThreshold},
+		{s.agentCrashEntry, &cfg.AgentCrashWindow},
+		{s.launchTimeoutEntry, &cfg.LaunchTimeout},
+	}
+	for _, d := range durations {
+		parsed, err := time.ParseDuration(strings.TrimSpace(d.entry.Text))
+		if err != nil {
+			return cfg, fmt.Errorf("invalid duration %q: %w", d.entry.Text, err)
+		}
+		*d.dest = config.Duration(parsed)
+	}
+
+	numbers := []struct {
+		entry *widget.Entry
+		dest  *int
+	}{
+		{s.agentInstancesEntry, &cfg.AgentMinInstances},
+		{s.maxLogLinesEntry, &cfg.MaxLogLines},
+		{s.launchRetriesEntry, &cfg.LaunchRetries},
+	}
+	for _, n := range numbers {
+		parsed, err := strconv.Atoi(strings.TrimSpace(n.entry.Text))
+		if err != nil {
+			return cfg, fmt.Errorf("invalid number %q: %w", n.entry.Text, err)
+		}
+		*n.dest = parsed
+	}
+
+	cfg.AgentStrategy = strategyName(s.agentStrategySelect.Selected)
+	cfg.AgentPath = strings.TrimSpace(s.agentPathEntry.Text)
+
+	// Keep enabled games whose definitions are missing, so that they are
+	// monitored again once their definition file is back
+	var enabled []string
+	for _, id := range cfg.EnabledGames {
+		if _, ok := s.parent.games.Get(id); !ok {
+			enabled = append(enabled, id)
+		}
+	}
+	for _, d := range s.parent.games.Definitions() {
+		if slices.Contains(s.gamesCheck.Selected, d.Label()) {
+			enabled = append(enabled, d.ID)
+		}
+	}
+	cfg.EnabledGames = enabled
+
+	for _, opt := range languageOptions() {
+		if opt.name == s.languageSelect.Selected {
+			cfg.Language = opt.code
+		}
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// onSave validates, saves and applies the settings
+func (s *settingsWindow) onSave() {
+	cfg, err := s.readConfig()
+	if err != nil {
+		dialog.ShowError(err, s.window)
+		return
+	}
+
+	if s.parent.configPath == "" {
+		dialog.ShowError(errors.New(i18n.Get("No location is available to save settings")), s.window)
+		return
+	}
+	if err := config.Save(s.parent.configPath, cfg); err != nil {
+		dialog.ShowError(err, s.window)
+		return
+	}
+
+	languageChanged := cfg.Language != s.parent.config().Language
+	s.parent.applyConfig(cfg)
+
+	s.parent.appendLog(i18n.Get("Settings saved and applied"))
+	if languageChanged {
+		s.parent.appendLog(i18n.Get("The new language takes effect after restarting Multiablo"))
+	}
+	s.window.Close()
+}
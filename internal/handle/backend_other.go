@@ -0,0 +1,27 @@
+//go:build !windows
+
+package handle
+
+// systemBackend reports ErrUnsupported for every operation on non-Windows systems
+type systemBackend struct{}
+
+// NewSystemBackend returns the Backend for the running operating system
+func NewSystemBackend() Backend {
+	return systemBackend{}
+}
+
+func (systemBackend) Snapshot([]uint32, []string) (*Snapshot, error) {
+	return nil, ErrUnsupported
+}
+
+func (systemBackend) CheckArchitecture() error {
+	return nil
+}
+
+func (systemBackend) CloseRemoteHandle(uint32, uintptr) error {
+	return ErrUnsupported
+}
+
+func (systemBackend) ProbeObject(string, string) (ObjectProbe, error) {
+	return ObjectProbe{}, ErrUnsupported
+}
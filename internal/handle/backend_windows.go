@@ -0,0 +1,67 @@
+//go:build windows
+
+package handle
+
+import (
+	"golang.org/x/sys/windows"
+)
+
+// systemBackend implements Backend with the native NT API
+type systemBackend struct {
+	// resolver runs the object name queries, which can hang on some handles
+	resolver NameResolver
+
+	// types caches the type names of object type indexes
+	types *typeCache
+
+	// err refuses every operation when the build does not match the system
+	err error
+}
+
+// NewSystemBackend returns the Backend for the running operating system
+func NewSystemBackend() Backend {
+	return systemBackend{
+		resolver: NewNameResolver(DefaultNameTimeout, DefaultMaxStuckWorkers),
+		types:    newTypeCache(),
+		err:      checkArchitecture(),
+	}
+}
+
+func (b systemBackend) Snapshot(processIDs []uint32, nameTypes []string) (*Snapshot, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	return snapshotHandles(processIDs, nameTypes, b.resolver, b.types)
+}
+
+func (b systemBackend) CloseRemoteHandle(processID uint32, handle uintptr) error {
+	if b.err != nil {
+		return b.err
+	}
+	return closeRemoteHandle(processID, windows.Handle(handle))
+}
+
+func (b systemBackend) CheckArchitecture() error {
+	return b.err
+}
+
+func (b systemBackend) ProbeObject(typeName, name string) (ObjectProbe, error) {
+	if b.err != nil {
+		return ObjectProbe{}, b.err
+	}
+	return probeObject(typeName, name)
+}
+
+// checkArchitecture reports ErrArchitectureMismatch when this process runs
+// under WOW64, where the handle table of 64-bit processes cannot be used
+func checkArchitecture() error {
+	var wow64 bool
+	if err := windows.IsWow64Process(windows.CurrentProcess(), &wow64); err != nil {
+		// Without an answer, assume the build matches as before
+		return nil
+	}
+	if wow64 {
+		return ErrArchitectureMismatch
+	}
+	return nil
+}
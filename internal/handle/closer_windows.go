@@ -1,5 +1,5 @@
-// Package handle provides utilities for enumerating and manipulating
-// Windows handles in remote processes.
+//go:build windows
+
 package handle
 
 import (
@@ -8,7 +8,7 @@ import (
 	"golang.org/x/sys/windows"
 )
 
-// CloseRemoteHandle closes a handle in a remote process
+// closeRemoteHandle closes a handle in a remote process
 func closeRemoteHandle(processID uint32, handle windows.Handle) error {
 	// Open the target process with PROCESS_DUP_HANDLE permission
 	processHandle, err := windows.OpenProcess(
@@ -41,34 +41,3 @@ func closeRemoteHandle(processID uint32, handle windows.Handle) error {
 
 	return nil
 }
-
-// CloseHandlesByName finds and closes all handles matching the given name in a process
-func CloseHandlesByName(processID uint32, handleName string) (int, error) {
-	// Find all handles matching the name
-	handles, err := findHandlesByName(processID, handleName)
-	if err != nil {
-		return 0, fmt.Errorf("failed to find handles: %w", err)
-	}
-
-	if len(handles) == 0 {
-		return 0, fmt.Errorf("no handles found with name: %s", handleName)
-	}
-
-	// Close each handle
-	closedCount := 0
-	var lastError error
-	for _, h := range handles {
-		err := closeRemoteHandle(h.ProcessID, h.Handle)
-		if err != nil {
-			lastError = err
-			continue
-		}
-		closedCount++
-	}
-
-	if closedCount == 0 && lastError != nil {
-		return 0, fmt.Errorf("failed to close any handles: %w", lastError)
-	}
-
-	return closedCount, nil
-}
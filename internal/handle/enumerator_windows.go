@@ -0,0 +1,330 @@
+//go:build windows
+
+//nolint:govet // This file requires unsafe pointer operations for Windows API interop with variable-length structures
+package handle
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	// initialBufferSize is the first buffer size tried for the system handle query
+	initialBufferSize = 1024 * 1024
+
+	// bufferSlack is added to the reported size, since the handle table keeps growing
+	bufferSlack = 1024 * 1024
+)
+
+// pointerSize is the pointer width of this build, which selects the table layout
+const pointerSize = int(unsafe.Sizeof(uintptr(0)))
+
+// lastBufferSize remembers the size the previous query needed,
+// so later snapshots usually succeed with a single call
+var lastBufferSize atomic.Uint32
+
+// handleEntry is a handle of a target process taken from the system handle table
+type handleEntry struct {
+	value         uintptr
+	typeIndex     uint16
+	grantedAccess uint32
+
+	// verify is set when the entry may belong to another process, so that
+	// only the handle duplicated from the target process can be trusted
+	verify bool
+}
+
+// snapshotHandles lists the handles of the given processes with their type names
+// using a single system-wide handle query for all of them.
+// Type names come from types where the type index is known already.
+// Names are only queried for the types in nameTypes, through resolver in case a query hangs
+func snapshotHandles(processIDs []uint32, nameTypes []string, resolver NameResolver, types *typeCache) (*Snapshot, error) {
+	table, err := querySystemHandleTable()
+	if err != nil {
+		return nil, err
+	}
+
+	named := make(map[string]bool, len(nameTypes))
+	for _, typeName := range nameTypes {
+		named[typeName] = true
+	}
+
+	// Index the handles of the target processes by PID in a single pass
+	snapshot := NewSnapshot()
+	targets, ambiguous := table.ByProcess(processIDs)
+	for _, pid := range ambiguous {
+		snapshot.Fail(pid, fmt.Errorf("failed to inspect process %d: %w", pid, ErrPIDCollision))
+	}
+	for pid, entries := range targets {
+		list := make([]handleEntry, len(entries))
+		for i, entry := range entries {
+			list[i] = handleEntry{
+				value:         uintptr(entry.HandleValue),
+				typeIndex:     entry.ObjectTypeIndex,
+				grantedAccess: entry.GrantedAccess,
+				// Legacy entries are matched on the low 16 bits of the PID only
+				verify: table.Layout() == LayoutLegacy,
+			}
+		}
+		inspectHandles(snapshot, named, resolver, types, pid, list)
+	}
+
+	return snapshot, nil
+}
+
+// querySystemHandleTable queries the system handle table in the layout of
+// this build's pointer width, falling back to the legacy information class
+// on systems that do not support the extended one
+func querySystemHandleTable() (*HandleTable, error) {
+	layout := LayoutExtended
+	buffer, err := querySystemHandles(SystemExtendedHandleInformation)
+	if errors.Is(err, syscall.Errno(StatusInvalidInfoClass)) {
+		layout = LayoutLegacy
+		buffer, err = querySystemHandles(SystemHandleInformation)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseHandleTable(buffer, layout, pointerSize)
+}
+
+// querySystemHandles copies the system handle table of an information class
+// into a buffer large enough to hold it
+func querySystemHandles(class uint32) ([]byte, error) {
+	bufferSize := max(lastBufferSize.Load(), initialBufferSize)
+	var buffer []byte
+	var returnLength uint32
+
+	// Query system handle information with increasing buffer size
+	for {
+		buffer = make([]byte, bufferSize)
+		err := ntQuerySystemInformation(
+			class,
+			uintptr(unsafe.Pointer(&buffer[0])),
+			bufferSize,
+			&returnLength,
+		)
+
+		if err == nil {
+			lastBufferSize.Store(bufferSize)
+			return buffer, nil
+		}
+
+		// If buffer is too small, increase it and retry
+		if errno, ok := err.(syscall.Errno); ok && errno == StatusInfoLengthMismatch {
+			bufferSize = returnLength + bufferSlack
+			continue
+		}
+
+		return nil, fmt.Errorf("ntQuerySystemInformation failed: %w", err)
+	}
+}
+
+// inspectHandles resolves the type of the given handles of a process, and
+// the name of those whose type is named, and adds them to the snapshot. Handles
+// are only duplicated when their type index is unknown, their name is needed
+// or the entry has to be verified. Entries to verify are described by the
+// duplicated handle alone and skipped when it cannot be duplicated.
+func inspectHandles(snapshot *Snapshot, named map[string]bool, resolver NameResolver, types *typeCache, processID uint32, entries []handleEntry) {
+	// Open the target process once
+	processHandle, err := windows.OpenProcess(
+		windows.PROCESS_DUP_HANDLE,
+		false,
+		processID,
+	)
+	if err != nil {
+		snapshot.Fail(processID, fmt.Errorf("failed to open process %d: %w", processID, err))
+		return
+	}
+	defer func() {
+		_ = windows.CloseHandle(processHandle)
+	}()
+
+	// Get current process handle for duplication
+	currentProcess := windows.CurrentProcess()
+
+	var handles []HandleInfo
+	verified := make(map[uintptr]bool)
+	for _, entry := range entries {
+		if entry.verify {
+			// Several processes may have listed the same handle value
+			if verified[entry.value] {
+				continue
+			}
+			verified[entry.value] = true
+		}
+
+		typeName, known := types.lookup(entry.typeIndex)
+		if entry.verify {
+			// The type index may be that of another process's object
+			typeName, known = "", false
+		}
+		if known && !named[typeName] {
+			// The type is known from its index, so the handle is not duplicated
+			handles = append(handles, HandleInfo{
+				ProcessID:     processID,
+				Handle:        entry.value,
+				TypeName:      typeName,
+				GrantedAccess: entry.grantedAccess,
+			})
+			continue
+		}
+
+		// Duplicate the handle to our process
+		var duplicatedHandle windows.Handle
+		err = ntDuplicateObject(
+			processHandle,
+			windows.Handle(entry.value),
+			currentProcess,
+			&duplicatedHandle,
+			0,
+			0,
+			DuplicateSameAccess,
+		)
+		if err != nil {
+			// Skip the handle, but remember that its name is unknown
+			if known {
+				snapshot.AddUnresolved(processID, typeName, err)
+			}
+			continue
+		}
+
+		// Learn the type of an unknown index from the first handle that has it
+		grantedAccess := entry.grantedAccess
+		if !known {
+			typeName = queryObjectType(duplicatedHandle)
+			if typeName != "" && !entry.verify {
+				types.learn(entry.typeIndex, typeName)
+			}
+		}
+		if entry.verify {
+			grantedAccess = queryGrantedAccess(duplicatedHandle)
+		}
+
+		// Only query names of the requested types to avoid hanging
+		var name string
+		if named[typeName] {
+			name, err = resolver.Resolve(func() string {
+				// The query closes the duplicated handle itself, since it may outlive the timeout
+				defer func() {
+					_ = windows.CloseHandle(duplicatedHandle)
+				}()
+				return queryObjectName(duplicatedHandle)
+			})
+			if errors.Is(err, ErrResolverBusy) {
+				// The query never ran, so it did not close the handle
+				_ = windows.CloseHandle(duplicatedHandle)
+			}
+			if err != nil {
+				snapshot.AddUnresolved(processID, typeName, err)
+			}
+		} else {
+			// Close the duplicated handle
+			_ = windows.CloseHandle(duplicatedHandle)
+		}
+
+		handles = append(handles, HandleInfo{
+			ProcessID:     processID,
+			Handle:        entry.value,
+			Name:          name,
+			TypeName:      typeName,
+			GrantedAccess: grantedAccess,
+		})
+	}
+
+	snapshot.Add(processID, handles)
+}
+
+// queryObjectType queries the type name of a handle
+func queryObjectType(handle windows.Handle) string {
+	// Allocate aligned buffer (using []uint64 ensures 8-byte alignment)
+	// We need 1024 bytes, so 128 uint64s
+	alignedBuf := make([]uint64, 128)
+	buffer := unsafe.Slice((*byte)(unsafe.Pointer(&alignedBuf[0])), len(alignedBuf)*8)
+	var returnLength uint32
+
+	err := ntQueryObject(
+		handle,
+		ObjectTypeInformation,
+		uintptr(unsafe.Pointer(&buffer[0])),
+		uint32(len(buffer)),
+		&returnLength,
+	)
+
+	if err != nil {
+		return ""
+	}
+
+	typeInfo := (*ObjectTypeInfo)(unsafe.Pointer(&buffer[0]))
+	return getUnicodeString(&typeInfo.TypeName)
+}
+
+// queryGrantedAccess queries the access a handle was granted; a handle
+// duplicated with DuplicateSameAccess has the access of its source
+func queryGrantedAccess(handle windows.Handle) uint32 {
+	var info ObjectBasicInfo
+	var returnLength uint32
+
+	err := ntQueryObject(
+		handle,
+		ObjectBasicInformation,
+		uintptr(unsafe.Pointer(&info)),
+		uint32(unsafe.Sizeof(info)),
+		&returnLength,
+	)
+	if err != nil {
+		return 0
+	}
+	return info.GrantedAccess
+}
+
+// queryObjectName queries the name of a handle.
+// It can hang on some handles, so it is run through a NameResolver.
+func queryObjectName(handle windows.Handle) string {
+	// Allocate aligned buffer (using []uint64 ensures 8-byte alignment)
+	// We need 4096 bytes, so 512 uint64s
+	alignedBuf := make([]uint64, 512)
+	buffer := unsafe.Slice((*byte)(unsafe.Pointer(&alignedBuf[0])), len(alignedBuf)*8)
+	var returnLength uint32
+
+	// Note: ntQueryObject can hang on certain handles (e.g., named pipes)
+	err := ntQueryObject(
+		handle,
+		ObjectNameInformation,
+		uintptr(unsafe.Pointer(&buffer[0])),
+		uint32(len(buffer)),
+		&returnLength,
+	)
+
+	if err != nil {
+		// If buffer is too small, try again with larger buffer
+		if errno, ok := err.(syscall.Errno); ok && errno == StatusInfoLengthMismatch {
+			// Align the new buffer as well
+			requiredUint64s := (returnLength + 7) / 8
+			alignedBuf = make([]uint64, requiredUint64s)
+			buffer = unsafe.Slice((*byte)(unsafe.Pointer(&alignedBuf[0])), len(alignedBuf)*8)
+
+			err = ntQueryObject(
+				handle,
+				ObjectNameInformation,
+				uintptr(unsafe.Pointer(&buffer[0])),
+				uint32(len(buffer)),
+				&returnLength,
+			)
+			if err != nil {
+				return ""
+			}
+		} else {
+			return ""
+		}
+	}
+
+	nameInfo := (*ObjectNameInfo)(unsafe.Pointer(&buffer[0]))
+	return getUnicodeString(&nameInfo.Name)
+}
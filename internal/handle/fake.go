@@ -0,0 +1,283 @@
+package handle
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Ensure Fake implements Backend
+var _ Backend = (*Fake)(nil)
+
+// errAccessDenied mirrors the error returned when a handle cannot be duplicated
+var errAccessDenied = errors.New("access denied")
+
+// FakeHandle describes a handle simulated by Fake
+type FakeHandle struct {
+	Value         uintptr
+	TypeName      string
+	Name          string
+	GrantedAccess uint32
+
+	// Inaccessible handles cannot be duplicated. Like on Windows, where
+	// types are known from their type index, they are only skipped by
+	// Snapshot when their name is requested.
+	Inaccessible bool
+
+	// CloseErr makes closing this handle fail
+	CloseErr error
+
+	// NameDelay simulates a slow object name query; a delay longer than
+	// the name resolver's timeout simulates a hung query
+	NameDelay time.Duration
+}
+
+// ClosedHandle records a handle closed through Fake
+type ClosedHandle struct {
+	ProcessID uint32
+	Handle    uintptr
+}
+
+// Fake is an in-memory Backend that simulates per-process handle tables.
+// Processes without a table behave like processes that cannot be opened.
+type Fake struct {
+	tables   map[uint32][]FakeHandle
+	openErrs map[uint32]error
+	closed   []ClosedHandle
+
+	// hidden are named objects kept alive by processes that are not
+	// simulated, keyed by type and name
+	hidden map[[2]string]bool
+
+	// snapshots counts the system-wide handle queries
+	snapshots int
+
+	// resolver runs the simulated name queries
+	resolver NameResolver
+
+	// archErr is returned by CheckArchitecture
+	archErr error
+
+	mu sync.Mutex
+}
+
+// NewFake creates a fake without any process handle tables
+func NewFake() *Fake {
+	return &Fake{
+		tables:   make(map[uint32][]FakeHandle),
+		openErrs: make(map[uint32]error),
+		hidden:   make(map[[2]string]bool),
+		resolver: NewNameResolver(DefaultNameTimeout, DefaultMaxStuckWorkers),
+	}
+}
+
+// SetNameResolver replaces the resolver that runs the simulated name queries
+func (f *Fake) SetNameResolver(resolver NameResolver) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.resolver = resolver
+}
+
+// SetHandles replaces the handle table of a process
+func (f *Fake) SetHandles(processID uint32, handles ...FakeHandle) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tables[processID] = append([]FakeHandle(nil), handles...)
+}
+
+// AddHandle appends a handle to the handle table of a process
+func (f *Fake) AddHandle(processID uint32, h FakeHandle) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tables[processID] = append(f.tables[processID], h)
+}
+
+// RemoveProcess drops the handle table of a process, as if it had exited
+func (f *Fake) RemoveProcess(processID uint32) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.tables, processID)
+}
+
+// SetProcessError makes opening the given process fail with err (nil clears it)
+func (f *Fake) SetProcessError(processID uint32, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err == nil {
+		delete(f.openErrs, processID)
+		return
+	}
+	f.openErrs[processID] = err
+}
+
+// KeepObject makes ProbeObject find a named object that is held by a process
+// whose handles are not simulated, so that its holders are unknown
+func (f *Fake) KeepObject(typeName, name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.hidden[[2]string{typeName, name}] = true
+}
+
+// Handles returns a copy of the current handle table of a process
+func (f *Fake) Handles(processID uint32) []FakeHandle {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FakeHandle(nil), f.tables[processID]...)
+}
+
+// Closed returns the handles closed so far, in order
+func (f *Fake) Closed() []ClosedHandle {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]ClosedHandle(nil), f.closed...)
+}
+
+// Snapshots returns how many snapshots were taken so far
+func (f *Fake) Snapshots() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.snapshots
+}
+
+// SetArchitectureError makes CheckArchitecture fail with err (nil clears it)
+func (f *Fake) SetArchitectureError(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.archErr = err
+}
+
+// CheckArchitecture returns the error set with SetArchitectureError
+func (f *Fake) CheckArchitecture() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.archErr
+}
+
+// Snapshot lists the accessible handles of the given processes.
+// Like the Windows backend, names are only reported for the types in nameTypes.
+func (f *Fake) Snapshot(processIDs []uint32, nameTypes []string) (*Snapshot, error) {
+	f.mu.Lock()
+	f.snapshots++
+	tables := make(map[uint32][]FakeHandle, len(processIDs))
+	errs := make(map[uint32]error)
+	for _, pid := range processIDs {
+		table, err := f.openLocked(pid)
+		if err != nil {
+			errs[pid] = err
+			continue
+		}
+		tables[pid] = append([]FakeHandle(nil), table...)
+	}
+	resolver := f.resolver
+	f.mu.Unlock()
+
+	snapshot := NewSnapshot()
+	for pid, err := range errs {
+		snapshot.Fail(pid, err)
+	}
+	for pid, table := range tables {
+		snapshot.Add(pid, inspect(snapshot, nameTypes, resolver, pid, table))
+	}
+	return snapshot, nil
+}
+
+// inspect converts the accessible handles of a fake handle table,
+// resolving the names of the types in nameTypes through resolver
+func inspect(snapshot *Snapshot, nameTypes []string, resolver NameResolver, processID uint32, table []FakeHandle) []HandleInfo {
+	var handles []HandleInfo
+	for _, h := range table {
+		named := slices.Contains(nameTypes, h.TypeName)
+		if h.Inaccessible && named {
+			snapshot.AddUnresolved(processID, h.TypeName, errAccessDenied)
+			continue
+		}
+
+		var name string
+		if named {
+			// Sleep outside the lock so slow queries do not block other callers
+			var err error
+			name, err = resolver.Resolve(func() string {
+				time.Sleep(h.NameDelay)
+				return h.Name
+			})
+			if err != nil {
+				snapshot.AddUnresolved(processID, h.TypeName, err)
+			}
+		}
+
+		handles = append(handles, HandleInfo{
+			ProcessID:     processID,
+			Handle:        h.Value,
+			Name:          name,
+			TypeName:      h.TypeName,
+			GrantedAccess: h.GrantedAccess,
+		})
+	}
+
+	return handles
+}
+
+// CloseRemoteHandle removes a handle from the handle table of a process
+func (f *Fake) CloseRemoteHandle(processID uint32, handle uintptr) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	table, err := f.openLocked(processID)
+	if err != nil {
+		return err
+	}
+
+	for i, h := range table {
+		if h.Value != handle {
+			continue
+		}
+		if h.CloseErr != nil {
+			return fmt.Errorf("failed to close handle 0x%X in process %d: %w", handle, processID, h.CloseErr)
+		}
+		if h.Inaccessible {
+			return fmt.Errorf("failed to close handle 0x%X in process %d: %w", handle, processID, errAccessDenied)
+		}
+		f.tables[processID] = append(table[:i], table[i+1:]...)
+		f.closed = append(f.closed, ClosedHandle{ProcessID: processID, Handle: handle})
+		return nil
+	}
+
+	return fmt.Errorf("failed to close handle 0x%X in process %d: invalid handle", handle, processID)
+}
+
+// ProbeObject reports the simulated processes holding a handle with the
+// given type and name, and the objects kept alive with KeepObject
+func (f *Fake) ProbeObject(typeName, name string) (ObjectProbe, error) {
+	if !slices.Contains(ProbeTypes, typeName) {
+		return ObjectProbe{}, fmt.Errorf("%w: %s", ErrProbeUnsupported, typeName)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	probe := ObjectProbe{Exists: f.hidden[[2]string{typeName, name}]}
+	for pid, table := range f.tables {
+		if slices.ContainsFunc(table, func(h FakeHandle) bool {
+			return h.TypeName == typeName && h.Name == name
+		}) {
+			probe.Exists = true
+			probe.Holders = append(probe.Holders, pid)
+		}
+	}
+	slices.Sort(probe.Holders)
+	return probe, nil
+}
+
+// openLocked returns the handle table of a process (caller must hold f.mu)
+func (f *Fake) openLocked(processID uint32) ([]FakeHandle, error) {
+	if err := f.openErrs[processID]; err != nil {
+		return nil, fmt.Errorf("failed to open process %d: %w", processID, err)
+	}
+	table, ok := f.tables[processID]
+	if !ok {
+		return nil, fmt.Errorf("failed to open process %d: not found", processID)
+	}
+	return table, nil
+}
@@ -0,0 +1,209 @@
+// Package handle provides utilities for enumerating and manipulating
+// Windows handles in remote processes.
+package handle
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrUnsupported is returned by the system backend on platforms other than Windows
+	ErrUnsupported = errors.New("handle management is only supported on Windows")
+
+	// ErrArchitectureMismatch is returned by the system backend when a 32-bit build runs on 64-bit Windows
+	ErrArchitectureMismatch = errors.New("this 32-bit build cannot manage handles on 64-bit Windows, use the 64-bit build")
+
+	// ErrNoHandles is returned when a process holds no handle with the requested name
+	ErrNoHandles = errors.New("no handles found matching")
+
+	// ErrProbeUnsupported is returned when objects of a type cannot be looked up by name
+	ErrProbeUnsupported = errors.New("object type cannot be probed")
+)
+
+// NamedObjectTypes are the object types whose names can be queried
+// without the risk of hanging, unlike File handles of named pipes
+var NamedObjectTypes = []string{
+	"Event", "Mutant", "Section", "Semaphore", "Timer",
+	"Job", "Key", "Directory", "SymbolicLink",
+}
+
+// ProbeTypes are the object types whose named objects can be looked up with ProbeObject
+var ProbeTypes = []string{"Event", "Mutant", "Semaphore"}
+
+// ObjectProbe is the result of looking up a named object
+type ObjectProbe struct {
+	// Exists is set when an object with the name still exists
+	Exists bool
+
+	// Holders are the processes with an open handle to the object.
+	// It can be empty although the object exists, when the system does
+	// not reveal which handles refer to it.
+	Holders []uint32
+}
+
+// HandleInfo represents information about a handle
+type HandleInfo struct {
+	ProcessID     uint32
+	Handle        uintptr
+	Name          string
+	TypeName      string
+	GrantedAccess uint32
+}
+
+// Snapshot holds the handles of several processes, taken from a single
+// system-wide handle query
+type Snapshot struct {
+	handles map[uint32][]HandleInfo
+	errs    map[uint32]error
+
+	// unresolved counts the handles whose name was needed but could not
+	// be resolved, by process and object type
+	unresolved map[uint32]map[string]int
+	timeouts   int
+	refused    int
+}
+
+// NewSnapshot creates an empty snapshot; backends fill it with Add, Fail
+// and AddUnresolved
+func NewSnapshot() *Snapshot {
+	return &Snapshot{
+		handles:    make(map[uint32][]HandleInfo),
+		errs:       make(map[uint32]error),
+		unresolved: make(map[uint32]map[string]int),
+	}
+}
+
+// Add records the inspected handles of a process
+func (s *Snapshot) Add(processID uint32, handles []HandleInfo) {
+	s.handles[processID] = append(s.handles[processID], handles...)
+}
+
+// Fail records that a process could not be inspected
+func (s *Snapshot) Fail(processID uint32, err error) {
+	s.errs[processID] = err
+}
+
+// AddUnresolved records a handle of a process whose name was needed but
+// could not be resolved: the name query timed out (ErrNameTimeout), was
+// refused (ErrResolverBusy), or the handle could not be opened (other errors)
+func (s *Snapshot) AddUnresolved(processID uint32, typeName string, err error) {
+	switch {
+	case errors.Is(err, ErrNameTimeout):
+		s.timeouts++
+	case errors.Is(err, ErrResolverBusy):
+		s.refused++
+	}
+
+	types, ok := s.unresolved[processID]
+	if !ok {
+		types = make(map[string]int)
+		s.unresolved[processID] = types
+	}
+	types[typeName]++
+}
+
+// Timeouts returns how many name queries timed out while taking the snapshot
+func (s *Snapshot) Timeouts() int {
+	return s.timeouts
+}
+
+// Refused returns how many name queries were refused while taking the
+// snapshot, because too many earlier queries were stuck
+func (s *Snapshot) Refused() int {
+	return s.refused
+}
+
+// Unresolved returns how many handles of a process with one of the given
+// object types have a name that could not be resolved. Such a handle may
+// match a rule although its name is empty in the snapshot.
+func (s *Snapshot) Unresolved(processID uint32, typeNames []string) int {
+	count := 0
+	for _, typeName := range typeNames {
+		count += s.unresolved[processID][typeName]
+	}
+	return count
+}
+
+// Handles returns the handles of a process, or the error that prevented
+// inspecting it. A process that holds no handles yields an empty list.
+func (s *Snapshot) Handles(processID uint32) ([]HandleInfo, error) {
+	if err := s.errs[processID]; err != nil {
+		return nil, err
+	}
+	return s.handles[processID], nil
+}
+
+// Find returns the handles of a process that match the matcher
+func (s *Snapshot) Find(processID uint32, matcher *Matcher) ([]HandleInfo, error) {
+	handles, err := s.Handles(processID)
+	if err != nil {
+		return nil, err
+	}
+
+	var matchedHandles []HandleInfo
+	for _, h := range handles {
+		if matcher.Match(h) {
+			matchedHandles = append(matchedHandles, h)
+		}
+	}
+
+	return matchedHandles, nil
+}
+
+// Backend abstracts the operating system handle operations used to close
+// single-instance handles, so that the logic can run against a fake on any OS
+type Backend interface {
+	// Snapshot lists the handles of the given processes with their object
+	// type, using one system-wide handle query for all of them. Name is only
+	// filled in for the object types in nameTypes, where it can be resolved
+	// in time; handles that cannot be inspected are skipped. Processes that
+	// cannot be opened are recorded as failed in the snapshot; an error is
+	// returned only when the query fails.
+	Snapshot(processIDs []uint32, nameTypes []string) (*Snapshot, error)
+
+	// CloseRemoteHandle closes a handle in a remote process
+	CloseRemoteHandle(processID uint32, handle uintptr) error
+
+	// CheckArchitecture reports ErrArchitectureMismatch when this build
+	// cannot read the handle table of the running system, which every other
+	// operation would then fail with
+	CheckArchitecture() error
+
+	// ProbeObject looks up a named object of one of the ProbeTypes by its
+	// full object name, such as \Sessions\1\BaseNamedObjects\Name, and
+	// lists the processes holding it. Other types yield ErrProbeUnsupported.
+	ProbeObject(typeName, name string) (ObjectProbe, error)
+}
+
+// CloseSnapshotHandles closes the handles matching the matcher that a
+// snapshot lists for a process
+func CloseSnapshotHandles(backend Backend, snapshot *Snapshot, processID uint32, matcher *Matcher) (int, error) {
+	// Find all handles matching the rules
+	handles, err := snapshot.Find(processID, matcher)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find handles: %w", err)
+	}
+
+	if len(handles) == 0 {
+		return 0, fmt.Errorf("%w: %s", ErrNoHandles, matcher)
+	}
+
+	// Close each handle
+	closedCount := 0
+	var lastError error
+	for _, h := range handles {
+		err := backend.CloseRemoteHandle(h.ProcessID, h.Handle)
+		if err != nil {
+			lastError = err
+			continue
+		}
+		closedCount++
+	}
+
+	if closedCount == 0 && lastError != nil {
+		return 0, fmt.Errorf("failed to close any handles: %w", lastError)
+	}
+
+	return closedCount, nil
+}
@@ -0,0 +1,96 @@
+package handle
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestCloseSnapshotHandles(t *testing.T) {
+	const pid = 100
+	errClose := errors.New("handle is protected")
+	errOpen := errors.New("access denied")
+
+	single := func(value uintptr, closeErr error) FakeHandle {
+		return FakeHandle{Value: value, TypeName: "Event", Name: "Single Instance", CloseErr: closeErr}
+	}
+	other := FakeHandle{Value: 0x40, TypeName: "Event", Name: "Other"}
+
+	tests := []struct {
+		name    string
+		handles []FakeHandle
+		openErr error
+		want    int
+		wantErr error
+		closed  []uintptr
+	}{
+		{
+			name:    "all closed",
+			handles: []FakeHandle{single(0x4, nil), other, single(0x8, nil)},
+			want:    2,
+			closed:  []uintptr{0x4, 0x8},
+		},
+		{
+			name:    "some closes fail",
+			handles: []FakeHandle{single(0x4, errClose), single(0x8, nil), single(0xc, errClose)},
+			want:    1,
+			closed:  []uintptr{0x8},
+		},
+		{
+			name:    "every close fails",
+			handles: []FakeHandle{single(0x4, errClose), single(0x8, errClose)},
+			wantErr: errClose,
+		},
+		{
+			name:    "no match",
+			handles: []FakeHandle{other},
+			wantErr: ErrNoHandles,
+		},
+		{
+			name:    "process not inspected",
+			handles: []FakeHandle{single(0x4, nil)},
+			openErr: errOpen,
+			wantErr: errOpen,
+		},
+	}
+
+	matcher, err := NewMatcher([]MatchRule{{Type: "Event", Match: MatchExact, Pattern: "Single Instance"}})
+	if err != nil {
+		t.Fatalf("NewMatcher() error = %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFake()
+			f.SetHandles(pid, tt.handles...)
+			f.SetProcessError(pid, tt.openErr)
+
+			snapshot, err := f.Snapshot([]uint32{pid}, matcher.Types())
+			if err != nil {
+				t.Fatalf("Snapshot() error = %v", err)
+			}
+
+			got, err := CloseSnapshotHandles(f, snapshot, pid, matcher)
+			if got != tt.want {
+				t.Errorf("CloseSnapshotHandles() = %d, want %d", got, tt.want)
+			}
+			switch {
+			case tt.wantErr == nil && err != nil:
+				t.Errorf("CloseSnapshotHandles() error = %v, want nil", err)
+			case !errors.Is(err, tt.wantErr):
+				t.Errorf("CloseSnapshotHandles() error = %v, want %v", err, tt.wantErr)
+			}
+
+			var closed []uintptr
+			for _, c := range f.Closed() {
+				if c.ProcessID != pid {
+					t.Errorf("closed a handle of process %d, want %d", c.ProcessID, pid)
+				}
+				closed = append(closed, c.Handle)
+			}
+			if !slices.Equal(closed, tt.closed) {
+				t.Errorf("closed handles %#x, want %#x", closed, tt.closed)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package handle
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+)
+
+// Filter selects handles for the handle inspector
+type Filter struct {
+	// TypeName keeps only handles of this object type; empty keeps all types
+	TypeName string
+
+	// NameRegex keeps only handles whose name matches; nil keeps all names
+	NameRegex *regexp.Regexp
+}
+
+// Match reports whether a handle passes the filter
+func (f Filter) Match(h HandleInfo) bool {
+	if f.TypeName != "" && h.TypeName != f.TypeName {
+		return false
+	}
+	if f.NameRegex != nil && !f.NameRegex.MatchString(h.Name) {
+		return false
+	}
+	return true
+}
+
+// FilterHandles returns the handles that pass the filter
+func FilterHandles(handles []HandleInfo, filter Filter) []HandleInfo {
+	matched := []HandleInfo{}
+	for _, h := range handles {
+		if filter.Match(h) {
+			matched = append(matched, h)
+		}
+	}
+	return matched
+}
+
+// handleRecord is the exported representation of a handle
+type handleRecord struct {
+	PID           uint32  `json:"pid"`
+	Handle        uintptr `json:"handle"`
+	Type          string  `json:"type"`
+	Name          string  `json:"name"`
+	GrantedAccess uint32  `json:"granted_access"`
+}
+
+// WriteJSON writes handles as an indented JSON array
+func WriteJSON(w io.Writer, handles []HandleInfo) error {
+	records := make([]handleRecord, 0, len(handles))
+	for _, h := range handles {
+		records = append(records, handleRecord{
+			PID:           h.ProcessID,
+			Handle:        h.Handle,
+			Type:          h.TypeName,
+			Name:          h.Name,
+			GrantedAccess: h.GrantedAccess,
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(records)
+}
+
+// WriteCSV writes handles as CSV with a header row; handle values and
+// access masks are written in hexadecimal
+func WriteCSV(w io.Writer, handles []HandleInfo) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"pid", "handle", "type", "name", "granted_access"})
+	for _, h := range handles {
+		_ = cw.Write([]string{
+			strconv.FormatUint(uint64(h.ProcessID), 10),
+			fmt.Sprintf("0x%X", h.Handle),
+			h.TypeName,
+			h.Name,
+			fmt.Sprintf("0x%08X", h.GrantedAccess),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,169 @@
+package handle
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// MatchMode is how a match rule compares a handle name with its pattern
+type MatchMode string
+
+const (
+	// MatchExact requires the name to equal the pattern
+	MatchExact MatchMode = "exact"
+	// MatchPrefix requires the name to start with the pattern
+	MatchPrefix MatchMode = "prefix"
+	// MatchSuffix requires the name to end with the pattern
+	MatchSuffix MatchMode = "suffix"
+	// MatchContains requires the name to contain the pattern
+	MatchContains MatchMode = "contains"
+	// MatchRegex requires the name to match the pattern as a regular expression
+	MatchRegex MatchMode = "regex"
+)
+
+// sessionPrefix matches the namespace Windows puts in front of named objects
+var sessionPrefix = regexp.MustCompile(`^\\(?:Sessions\\\d+\\)?BaseNamedObjects\\`)
+
+// MatchRule selects handles by object type and name
+type MatchRule struct {
+	// Type is the object type the handle must have, e.g. "Event" or "Mutant"
+	Type string `json:"type"`
+
+	// Match is how the name is compared with Pattern
+	Match MatchMode `json:"match"`
+
+	// Pattern is the name, or the regular expression, to match
+	Pattern string `json:"pattern"`
+
+	// NormalizeSession strips the \Sessions\N\BaseNamedObjects\ or
+	// \BaseNamedObjects\ prefix from the name before matching
+	NormalizeSession bool `json:"normalize_session"`
+}
+
+// NormalizeName strips the session namespace prefix from an object name
+func NormalizeName(name string) string {
+	return sessionPrefix.ReplaceAllString(name, "")
+}
+
+// Matcher selects handles matching any of a set of rules
+type Matcher struct {
+	rules []compiledRule
+}
+
+// compiledRule is a match rule with its regular expression compiled
+type compiledRule struct {
+	MatchRule
+	re *regexp.Regexp
+}
+
+// NewMatcher checks and compiles match rules
+func NewMatcher(rules []MatchRule) (*Matcher, error) {
+	if len(rules) == 0 {
+		return nil, errors.New("at least one match rule is required")
+	}
+
+	m := &Matcher{}
+	var errs []error
+	for i, rule := range rules {
+		compiled, err := compileRule(rule)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rule %d: %w", i, err))
+			continue
+		}
+		m.rules = append(m.rules, compiled)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// compileRule checks a match rule and compiles its regular expression
+func compileRule(rule MatchRule) (compiledRule, error) {
+	compiled := compiledRule{MatchRule: rule}
+
+	if strings.TrimSpace(rule.Type) == "" {
+		return compiled, errors.New("type: must not be empty")
+	}
+	if rule.Pattern == "" {
+		return compiled, errors.New("pattern: must not be empty")
+	}
+
+	switch rule.Match {
+	case MatchExact, MatchPrefix, MatchSuffix, MatchContains:
+	case MatchRegex:
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return compiled, fmt.Errorf("pattern: %w", err)
+		}
+		compiled.re = re
+	default:
+		return compiled, fmt.Errorf("match: must be exact, prefix, suffix, contains or regex (got %q)", rule.Match)
+	}
+
+	return compiled, nil
+}
+
+// Match reports whether a handle matches any rule. Handles without a
+// resolved name never match.
+func (m *Matcher) Match(h HandleInfo) bool {
+	if h.Name == "" {
+		return false
+	}
+	for _, rule := range m.rules {
+		if rule.matches(h) {
+			return true
+		}
+	}
+	return false
+}
+
+// Types returns the object types whose names the rules need
+func (m *Matcher) Types() []string {
+	var types []string
+	for _, rule := range m.rules {
+		if !slices.Contains(types, rule.Type) {
+			types = append(types, rule.Type)
+		}
+	}
+	return types
+}
+
+// String describes the rules for messages
+func (m *Matcher) String() string {
+	parts := make([]string, 0, len(m.rules))
+	for _, rule := range m.rules {
+		parts = append(parts, fmt.Sprintf("%s %s %q", rule.Type, rule.Match, rule.Pattern))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// matches reports whether a handle matches the rule
+func (r compiledRule) matches(h HandleInfo) bool {
+	if h.TypeName != r.Type {
+		return false
+	}
+
+	name := h.Name
+	if r.NormalizeSession {
+		name = NormalizeName(name)
+	}
+
+	switch r.Match {
+	case MatchExact:
+		return name == r.Pattern
+	case MatchPrefix:
+		return strings.HasPrefix(name, r.Pattern)
+	case MatchSuffix:
+		return strings.HasSuffix(name, r.Pattern)
+	case MatchContains:
+		return strings.Contains(name, r.Pattern)
+	case MatchRegex:
+		return r.re.MatchString(name)
+	default:
+		return false
+	}
+}
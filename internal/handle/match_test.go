@@ -0,0 +1,155 @@
+package handle
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMatcher(t *testing.T) {
+	const sessionName = `\Sessions\1\BaseNamedObjects\DiabloII Check For Other Instances`
+
+	event := func(name string) HandleInfo {
+		return HandleInfo{TypeName: "Event", Name: name}
+	}
+
+	tests := []struct {
+		name   string
+		rule   MatchRule
+		handle HandleInfo
+		want   bool
+	}{
+		{
+			name:   "exact",
+			rule:   MatchRule{Type: "Event", Match: MatchExact, Pattern: sessionName},
+			handle: event(sessionName),
+			want:   true,
+		},
+		{
+			name:   "exact without normalization keeps the session prefix",
+			rule:   MatchRule{Type: "Event", Match: MatchExact, Pattern: "DiabloII Check For Other Instances"},
+			handle: event(sessionName),
+			want:   false,
+		},
+		{
+			name:   "exact with normalization",
+			rule:   MatchRule{Type: "Event", Match: MatchExact, Pattern: "DiabloII Check For Other Instances", NormalizeSession: true},
+			handle: event(sessionName),
+			want:   true,
+		},
+		{
+			name:   "normalization of the global namespace",
+			rule:   MatchRule{Type: "Event", Match: MatchExact, Pattern: "Global Event", NormalizeSession: true},
+			handle: event(`\BaseNamedObjects\Global Event`),
+			want:   true,
+		},
+		{
+			name:   "prefix",
+			rule:   MatchRule{Type: "Event", Match: MatchPrefix, Pattern: "DiabloII", NormalizeSession: true},
+			handle: event(sessionName),
+			want:   true,
+		},
+		{
+			name:   "suffix",
+			rule:   MatchRule{Type: "Event", Match: MatchSuffix, Pattern: "Other Instances"},
+			handle: event(sessionName),
+			want:   true,
+		},
+		{
+			name:   "contains",
+			rule:   MatchRule{Type: "Event", Match: MatchContains, Pattern: "Check For"},
+			handle: event(sessionName),
+			want:   true,
+		},
+		{
+			name:   "contains is case sensitive",
+			rule:   MatchRule{Type: "Event", Match: MatchContains, Pattern: "check for"},
+			handle: event(sessionName),
+			want:   false,
+		},
+		{
+			name:   "regex",
+			rule:   MatchRule{Type: "Event", Match: MatchRegex, Pattern: `^DiabloII .* Instances$`, NormalizeSession: true},
+			handle: event(sessionName),
+			want:   true,
+		},
+		{
+			name:   "other type",
+			rule:   MatchRule{Type: "Mutant", Match: MatchContains, Pattern: "DiabloII"},
+			handle: event(sessionName),
+			want:   false,
+		},
+		{
+			name:   "unresolved name",
+			rule:   MatchRule{Type: "Event", Match: MatchRegex, Pattern: `.*`},
+			handle: event(""),
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewMatcher([]MatchRule{tt.rule})
+			if err != nil {
+				t.Fatalf("NewMatcher() error = %v", err)
+			}
+			if got := m.Match(tt.handle); got != tt.want {
+				t.Errorf("Match(%q) = %v, want %v", tt.handle.Name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatcherAnyRuleAndTypes(t *testing.T) {
+	m, err := NewMatcher([]MatchRule{
+		{Type: "Event", Match: MatchExact, Pattern: "first"},
+		{Type: "Mutant", Match: MatchPrefix, Pattern: "second"},
+		{Type: "Event", Match: MatchSuffix, Pattern: "third"},
+	})
+	if err != nil {
+		t.Fatalf("NewMatcher() error = %v", err)
+	}
+
+	if got, want := m.Types(), []string{"Event", "Mutant"}; !slices.Equal(got, want) {
+		t.Errorf("Types() = %v, want %v", got, want)
+	}
+	for _, h := range []HandleInfo{
+		{TypeName: "Event", Name: "first"},
+		{TypeName: "Mutant", Name: "second mutant"},
+		{TypeName: "Event", Name: "the third"},
+	} {
+		if !m.Match(h) {
+			t.Errorf("Match(%s %q) = false, want true", h.TypeName, h.Name)
+		}
+	}
+	if h := (HandleInfo{TypeName: "Mutant", Name: "first"}); m.Match(h) {
+		t.Errorf("Match(%s %q) = true, want false", h.TypeName, h.Name)
+	}
+}
+
+func TestNewMatcherErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []MatchRule
+	}{
+		{name: "no rules"},
+		{name: "empty type", rules: []MatchRule{{Type: " ", Match: MatchExact, Pattern: "name"}}},
+		{name: "empty pattern", rules: []MatchRule{{Type: "Event", Match: MatchExact}}},
+		{name: "unknown mode", rules: []MatchRule{{Type: "Event", Match: "glob", Pattern: "name*"}}},
+		{name: "invalid regex", rules: []MatchRule{{Type: "Event", Match: MatchRegex, Pattern: "("}}},
+		{
+			name: "one invalid rule among valid ones",
+			rules: []MatchRule{
+				{Type: "Event", Match: MatchExact, Pattern: "name"},
+				{Type: "Event", Match: MatchRegex, Pattern: "["},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if m, err := NewMatcher(tt.rules); err == nil {
+				t.Errorf("NewMatcher() = %v, want an error", m)
+			}
+		})
+	}
+}
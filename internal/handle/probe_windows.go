@@ -0,0 +1,104 @@
+//go:build windows
+
+package handle
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// openProcs are the procedures that open named objects by type
+var openProcs = map[string]*windows.LazyProc{
+	"Event":     procNtOpenEvent,
+	"Mutant":    procNtOpenMutant,
+	"Semaphore": procNtOpenSemaphore,
+}
+
+// probeObject opens a named object by its full object name. While it is
+// open, the system handle table tells which other processes refer to the
+// same kernel object.
+func probeObject(typeName, name string) (ObjectProbe, error) {
+	proc, ok := openProcs[typeName]
+	if !ok {
+		return ObjectProbe{}, fmt.Errorf("%w: %s", ErrProbeUnsupported, typeName)
+	}
+
+	objectName, err := windows.NewNTUnicodeString(name)
+	if err != nil {
+		return ObjectProbe{}, fmt.Errorf("invalid object name %q: %w", name, err)
+	}
+	attributes := windows.OBJECT_ATTRIBUTES{ObjectName: objectName}
+	attributes.Length = uint32(unsafe.Sizeof(attributes))
+
+	var objectHandle windows.Handle
+	err = ntOpenObject(proc, &objectHandle, windows.SYNCHRONIZE, &attributes)
+	switch {
+	case errors.Is(err, syscall.Errno(StatusObjectNameNotFound)),
+		errors.Is(err, syscall.Errno(StatusObjectPathNotFound)):
+		return ObjectProbe{}, nil
+	case errors.Is(err, syscall.Errno(StatusAccessDenied)):
+		// The object exists, but it cannot be opened to find its holders
+		return ObjectProbe{Exists: true}, nil
+	case err != nil:
+		return ObjectProbe{}, fmt.Errorf("failed to open %s %q: %w", typeName, name, err)
+	}
+	defer func() {
+		_ = windows.CloseHandle(objectHandle)
+	}()
+
+	holders, err := objectHolders(objectHandle)
+	if err != nil {
+		return ObjectProbe{Exists: true}, err
+	}
+	return ObjectProbe{Exists: true, Holders: holders}, nil
+}
+
+// objectHolders returns the other processes with a handle to the object
+// behind a handle of this process. The object address is hidden from
+// processes without the debug privilege on recent Windows versions, in
+// which case no holders are found.
+func objectHolders(objectHandle windows.Handle) ([]uint32, error) {
+	table, err := querySystemHandleTable()
+	if err != nil {
+		return nil, err
+	}
+
+	self := windows.GetCurrentProcessId()
+	legacy := table.Layout() == LayoutLegacy
+	samePID := func(pid uint32) bool {
+		if legacy {
+			return uint16(pid) == uint16(self)
+		}
+		return pid == self
+	}
+
+	var object uint64
+	for i := range table.Len() {
+		entry := table.Entry(i)
+		if !samePID(entry.ProcessID) {
+			continue
+		}
+		if entry.HandleValue == uint64(objectHandle) || (legacy && uint16(entry.HandleValue) == uint16(objectHandle)) {
+			object = entry.Object
+			break
+		}
+	}
+	if object == 0 {
+		return nil, nil
+	}
+
+	var holders []uint32
+	for i := range table.Len() {
+		entry := table.Entry(i)
+		if entry.Object == object && !samePID(entry.ProcessID) && !slices.Contains(holders, entry.ProcessID) {
+			holders = append(holders, entry.ProcessID)
+		}
+	}
+	slices.Sort(holders)
+	return holders, nil
+}
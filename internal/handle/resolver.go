@@ -0,0 +1,140 @@
+package handle
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultNameTimeout is how long a single object name query may take
+	DefaultNameTimeout = 250 * time.Millisecond
+
+	// DefaultMaxStuckWorkers is how many timed out queries may still be running
+	// before further queries are refused
+	DefaultMaxStuckWorkers = 4
+
+	// workerIdleTimeout is how long an idle worker waits for a query before exiting
+	workerIdleTimeout = time.Minute
+)
+
+var (
+	// ErrNameTimeout is returned when an object name query did not finish in time
+	ErrNameTimeout = errors.New("object name query timed out")
+
+	// ErrResolverBusy is returned while too many timed out queries are still running
+	ErrResolverBusy = errors.New("too many object name queries are stuck")
+)
+
+// NameResolver runs object name queries that may never return, such as
+// NtQueryObject on some handles, without blocking the caller indefinitely
+type NameResolver interface {
+	// Resolve runs query and returns its result, or an error when the query
+	// did not finish in time (ErrNameTimeout) or was not started at all
+	// (ErrResolverBusy). A query that timed out keeps running in the
+	// background, so it must own everything it uses, including the handle it
+	// queries; after ErrResolverBusy the caller still owns them.
+	Resolve(query func() string) (string, error)
+
+	// Timeouts returns how many queries timed out so far
+	Timeouts() int
+}
+
+// nameJob is a query handed to a worker
+type nameJob struct {
+	query  func() string
+	result chan string
+}
+
+// workerResolver runs every query on a dedicated worker thread and stops
+// waiting for it after a timeout. Workers stuck in a query are replaced
+// until maxStuck of them are stuck at the same time.
+type workerResolver struct {
+	timeout  time.Duration
+	maxStuck int
+
+	// jobs hands queries to idle workers
+	jobs chan nameJob
+
+	stuck    int
+	timeouts int
+	mu       sync.Mutex
+}
+
+// NewNameResolver creates a NameResolver that gives each query timeout to
+// finish and refuses queries while maxStuck timed out queries are still running
+func NewNameResolver(timeout time.Duration, maxStuck int) NameResolver {
+	return &workerResolver{
+		timeout:  timeout,
+		maxStuck: max(maxStuck, 1),
+		jobs:     make(chan nameJob),
+	}
+}
+
+// Resolve runs query on an idle worker, or on a new one if none is idle
+func (r *workerResolver) Resolve(query func() string) (string, error) {
+	r.mu.Lock()
+	if r.stuck >= r.maxStuck {
+		r.mu.Unlock()
+		return "", ErrResolverBusy
+	}
+	r.mu.Unlock()
+
+	job := nameJob{query: query, result: make(chan string, 1)}
+	select {
+	case r.jobs <- job:
+	default:
+		go r.worker(job)
+	}
+
+	timer := time.NewTimer(r.timeout)
+	defer timer.Stop()
+
+	select {
+	case name := <-job.result:
+		return name, nil
+	case <-timer.C:
+	}
+
+	r.mu.Lock()
+	r.timeouts++
+	r.stuck++
+	r.mu.Unlock()
+
+	// The worker becomes available again if the query ever returns
+	go func() {
+		<-job.result
+		r.mu.Lock()
+		r.stuck--
+		r.mu.Unlock()
+	}()
+
+	return "", ErrNameTimeout
+}
+
+// Timeouts returns how many queries timed out so far
+func (r *workerResolver) Timeouts() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.timeouts
+}
+
+// worker runs job and then further jobs until it has been idle for workerIdleTimeout
+func (r *workerResolver) worker(job nameJob) {
+	// Keep the queries on one OS thread, so a hung system call only blocks
+	// this worker. The thread is not unlocked, so it exits with the worker.
+	runtime.LockOSThread()
+
+	for {
+		job.result <- job.query()
+
+		idle := time.NewTimer(workerIdleTimeout)
+		select {
+		case job = <-r.jobs:
+			idle.Stop()
+		case <-idle.C:
+			return
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package handle
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testNameTimeout = 50 * time.Millisecond
+
+// blockingQuery returns a name query that blocks until release is closed
+func blockingQuery(release <-chan struct{}) func() string {
+	return func() string {
+		<-release
+		return "released"
+	}
+}
+
+func TestResolverTimeout(t *testing.T) {
+	r := NewNameResolver(testNameTimeout, 2)
+	release := make(chan struct{})
+	defer close(release)
+
+	if name, err := r.Resolve(func() string { return "quick" }); name != "quick" || err != nil {
+		t.Fatalf("Resolve(quick) = %q, %v, want %q", name, err, "quick")
+	}
+
+	start := time.Now()
+	name, err := r.Resolve(blockingQuery(release))
+	if !errors.Is(err, ErrNameTimeout) || name != "" {
+		t.Fatalf("Resolve(hung) = %q, %v, want %v", name, err, ErrNameTimeout)
+	}
+	if elapsed := time.Since(start); elapsed < testNameTimeout {
+		t.Errorf("Resolve(hung) returned after %s, before the %s timeout", elapsed, testNameTimeout)
+	}
+	if got := r.Timeouts(); got != 1 {
+		t.Errorf("Timeouts() = %d, want 1", got)
+	}
+}
+
+func TestResolverBusyAndRecovery(t *testing.T) {
+	const maxStuck = 2
+	r := NewNameResolver(testNameTimeout, maxStuck)
+	release := make(chan struct{})
+
+	for i := 0; i < maxStuck; i++ {
+		if _, err := r.Resolve(blockingQuery(release)); !errors.Is(err, ErrNameTimeout) {
+			t.Fatalf("Resolve(hung #%d) error = %v, want %v", i+1, err, ErrNameTimeout)
+		}
+	}
+
+	// Further queries are refused without being run while the workers are stuck
+	var ran atomic.Bool
+	start := time.Now()
+	_, err := r.Resolve(func() string {
+		ran.Store(true)
+		return "refused"
+	})
+	if !errors.Is(err, ErrResolverBusy) {
+		t.Fatalf("Resolve() with %d stuck workers error = %v, want %v", maxStuck, err, ErrResolverBusy)
+	}
+	if elapsed := time.Since(start); elapsed >= testNameTimeout {
+		t.Errorf("refused query took %s, want an immediate refusal", elapsed)
+	}
+	if ran.Load() {
+		t.Error("a refused query was run")
+	}
+	if got := r.Timeouts(); got != maxStuck {
+		t.Errorf("Timeouts() = %d, want %d", got, maxStuck)
+	}
+
+	// Once the hung queries return, queries are accepted again
+	close(release)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		name, err := r.Resolve(func() string { return "recovered" })
+		if err == nil {
+			if name != "recovered" {
+				t.Errorf("Resolve() after recovery = %q, want %q", name, "recovered")
+			}
+			break
+		}
+		if !errors.Is(err, ErrResolverBusy) {
+			t.Fatalf("Resolve() after recovery error = %v, want nil or %v", err, ErrResolverBusy)
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the resolver did not recover after the hung queries returned")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if got := r.Timeouts(); got != maxStuck {
+		t.Errorf("Timeouts() = %d after recovery, want %d", got, maxStuck)
+	}
+}
+
+func TestSnapshotUnresolvedNames(t *testing.T) {
+	const pid = 100
+	slow := 10 * testNameTimeout
+
+	f := NewFake()
+	f.SetNameResolver(NewNameResolver(testNameTimeout, 2))
+	f.SetHandles(pid,
+		FakeHandle{Value: 0x4, TypeName: "Event", Name: "quick"},
+		FakeHandle{Value: 0x8, TypeName: "Event", Name: "slow event", NameDelay: slow},
+		FakeHandle{Value: 0xc, TypeName: "Mutant", Name: "slow mutant", NameDelay: slow},
+		// Both workers are stuck now, so this query is refused
+		FakeHandle{Value: 0x10, TypeName: "Event", Name: "refused"},
+		// Names of other types are not queried at all
+		FakeHandle{Value: 0x14, TypeName: "File", Name: "unqueried", NameDelay: slow},
+	)
+
+	snapshot, err := f.Snapshot([]uint32{pid}, []string{"Event", "Mutant"})
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+
+	if got := snapshot.Timeouts(); got != 2 {
+		t.Errorf("Timeouts() = %d, want 2", got)
+	}
+	if got := snapshot.Refused(); got != 1 {
+		t.Errorf("Refused() = %d, want 1", got)
+	}
+	unresolved := []struct {
+		types []string
+		want  int
+	}{
+		{types: []string{"Event"}, want: 2},
+		{types: []string{"Mutant"}, want: 1},
+		{types: []string{"Event", "Mutant"}, want: 3},
+		{types: []string{"File"}, want: 0},
+	}
+	for _, u := range unresolved {
+		if got := snapshot.Unresolved(pid, u.types); got != u.want {
+			t.Errorf("Unresolved(%v) = %d, want %d", u.types, got, u.want)
+		}
+	}
+	if got := snapshot.Unresolved(pid+1, []string{"Event"}); got != 0 {
+		t.Errorf("Unresolved() of another process = %d, want 0", got)
+	}
+
+	handles, err := snapshot.Handles(pid)
+	if err != nil {
+		t.Fatalf("Handles() error = %v", err)
+	}
+	want := map[uintptr]string{0x4: "quick", 0x8: "", 0xc: "", 0x10: "", 0x14: ""}
+	if len(handles) != len(want) {
+		t.Fatalf("Handles() = %d handles, want %d", len(handles), len(want))
+	}
+	for _, h := range handles {
+		if h.Name != want[h.Handle] {
+			t.Errorf("handle %#x has name %q, want %q", h.Handle, h.Name, want[h.Handle])
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package handle
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var (
+	// ErrInvalidTable is returned when a system handle information buffer cannot be decoded
+	ErrInvalidTable = errors.New("invalid system handle information buffer")
+
+	// ErrPIDCollision is returned for processes that cannot be told apart in a
+	// legacy handle table, because their PIDs share the low 16 bits it keeps
+	ErrPIDCollision = errors.New("process IDs share their low 16 bits in the legacy handle table")
+)
+
+// TableLayout identifies the format of a system handle information buffer
+type TableLayout int
+
+const (
+	// LayoutExtended is SYSTEM_HANDLE_INFORMATION_EX, returned for SystemExtendedHandleInformation
+	LayoutExtended TableLayout = iota
+	// LayoutLegacy is SYSTEM_HANDLE_INFORMATION, returned for SystemHandleInformation.
+	// It stores process IDs and handle values in 16 bits.
+	LayoutLegacy
+)
+
+// String returns the name of the table layout
+func (l TableLayout) String() string {
+	switch l {
+	case LayoutExtended:
+		return "extended"
+	case LayoutLegacy:
+		return "legacy"
+	default:
+		return "unknown"
+	}
+}
+
+// SystemHandleEntry is a decoded entry of the system handle table
+type SystemHandleEntry struct {
+	Object                uint64
+	ProcessID             uint32
+	HandleValue           uint64
+	GrantedAccess         uint32
+	CreatorBackTraceIndex uint16
+	ObjectTypeIndex       uint16
+	HandleAttributes      uint32
+}
+
+// HandleTable decodes the entries of a system handle information buffer on demand
+type HandleTable struct {
+	buffer      []byte
+	layout      TableLayout
+	pointerSize int
+	headerSize  int
+	entrySize   int
+	count       int
+}
+
+// ParseHandleTable checks that buffer holds a complete system handle table in
+// the given layout, written by a system with the given pointer size (4 or 8).
+// Entries are decoded by HandleTable.Entry without any unsafe casts.
+func ParseHandleTable(buffer []byte, layout TableLayout, pointerSize int) (*HandleTable, error) {
+	if pointerSize != 4 && pointerSize != 8 {
+		return nil, fmt.Errorf("%w: unsupported pointer size %d", ErrInvalidTable, pointerSize)
+	}
+
+	t := &HandleTable{
+		buffer:      buffer,
+		layout:      layout,
+		pointerSize: pointerSize,
+	}
+
+	switch layout {
+	case LayoutExtended:
+		// ULONG_PTR NumberOfHandles, ULONG_PTR Reserved
+		t.headerSize = 2 * pointerSize
+		// PVOID Object, ULONG_PTR UniqueProcessId, ULONG_PTR HandleValue,
+		// ULONG GrantedAccess, USHORT CreatorBackTraceIndex, USHORT ObjectTypeIndex,
+		// ULONG HandleAttributes, ULONG Reserved
+		t.entrySize = 3*pointerSize + 16
+	case LayoutLegacy:
+		// ULONG NumberOfHandles, padded to the alignment of the entries
+		t.headerSize = pointerSize
+		// USHORT UniqueProcessId, USHORT CreatorBackTraceIndex, UCHAR ObjectTypeIndex,
+		// UCHAR HandleAttributes, USHORT HandleValue, PVOID Object, ULONG GrantedAccess
+		t.entrySize = alignUp(8+pointerSize+4, pointerSize)
+	default:
+		return nil, fmt.Errorf("%w: unknown layout %d", ErrInvalidTable, layout)
+	}
+
+	if len(buffer) < t.headerSize {
+		return nil, fmt.Errorf("%w: %d bytes is shorter than the header", ErrInvalidTable, len(buffer))
+	}
+
+	var count uint64
+	if layout == LayoutLegacy {
+		count = uint64(binary.LittleEndian.Uint32(buffer))
+	} else {
+		count = t.readPointer(0)
+	}
+
+	// Compare by division so a corrupt count cannot overflow
+	if count > uint64((len(buffer)-t.headerSize)/t.entrySize) {
+		return nil, fmt.Errorf("%w: %d entries do not fit in %d bytes", ErrInvalidTable, count, len(buffer))
+	}
+	t.count = int(count)
+
+	return t, nil
+}
+
+// Layout returns the layout the table was decoded with
+func (t *HandleTable) Layout() TableLayout {
+	return t.layout
+}
+
+// Len returns the number of entries
+func (t *HandleTable) Len() int {
+	return t.count
+}
+
+// Entry decodes the entry at index, which must be in [0, Len())
+func (t *HandleTable) Entry(index int) SystemHandleEntry {
+	offset := t.headerSize + index*t.entrySize
+	entry := t.buffer[offset : offset+t.entrySize]
+	p := t.pointerSize
+
+	if t.layout == LayoutLegacy {
+		return SystemHandleEntry{
+			ProcessID:             uint32(binary.LittleEndian.Uint16(entry[0:])),
+			CreatorBackTraceIndex: binary.LittleEndian.Uint16(entry[2:]),
+			ObjectTypeIndex:       uint16(entry[4]),
+			HandleAttributes:      uint32(entry[5]),
+			HandleValue:           uint64(binary.LittleEndian.Uint16(entry[6:])),
+			Object:                t.readPointer(offset + 8),
+			GrantedAccess:         binary.LittleEndian.Uint32(entry[8+p:]),
+		}
+	}
+
+	return SystemHandleEntry{
+		Object:                t.readPointer(offset),
+		ProcessID:             uint32(t.readPointer(offset + p)),
+		HandleValue:           t.readPointer(offset + 2*p),
+		GrantedAccess:         binary.LittleEndian.Uint32(entry[3*p:]),
+		CreatorBackTraceIndex: binary.LittleEndian.Uint16(entry[3*p+4:]),
+		ObjectTypeIndex:       binary.LittleEndian.Uint16(entry[3*p+6:]),
+		HandleAttributes:      binary.LittleEndian.Uint32(entry[3*p+8:]),
+	}
+}
+
+// Entries decodes all entries
+func (t *HandleTable) Entries() []SystemHandleEntry {
+	entries := make([]SystemHandleEntry, t.count)
+	for i := range entries {
+		entries[i] = t.Entry(i)
+	}
+	return entries
+}
+
+// ByProcess returns the entries of the given processes, indexed by PID in a
+// single pass over the table. Every requested PID has a key, with no entries
+// when the table lists none for it.
+//
+// The legacy layout only keeps the low 16 bits of PIDs. Requested PIDs that
+// share them cannot be told apart, so they are returned in ambiguous instead
+// of targets. The entries of the other PIDs may still belong to processes
+// that were not requested, and must be checked against the full PID.
+func (t *HandleTable) ByProcess(processIDs []uint32) (targets map[uint32][]SystemHandleEntry, ambiguous []uint32) {
+	targets = make(map[uint32][]SystemHandleEntry, len(processIDs))
+	for _, pid := range processIDs {
+		targets[pid] = nil
+	}
+
+	// legacyPIDs maps the low 16 bits to the only requested PID that has them
+	var legacyPIDs map[uint16]uint32
+	if t.layout == LayoutLegacy {
+		legacyPIDs = make(map[uint16]uint32, len(processIDs))
+		shared := make(map[uint16]bool)
+		for pid := range targets {
+			if _, taken := legacyPIDs[uint16(pid)]; taken {
+				shared[uint16(pid)] = true
+			}
+			legacyPIDs[uint16(pid)] = pid
+		}
+		for pid := range targets {
+			if shared[uint16(pid)] {
+				delete(targets, pid)
+				delete(legacyPIDs, uint16(pid))
+				ambiguous = append(ambiguous, pid)
+			}
+		}
+		slices.Sort(ambiguous)
+	}
+
+	for i := range t.count {
+		entry := t.Entry(i)
+		pid := entry.ProcessID
+		if legacyPIDs != nil {
+			full, ok := legacyPIDs[uint16(pid)]
+			if !ok {
+				continue
+			}
+			pid = full
+		}
+		if list, ok := targets[pid]; ok {
+			targets[pid] = append(list, entry)
+		}
+	}
+	return targets, ambiguous
+}
+
+// readPointer reads a pointer-sized value at offset
+func (t *HandleTable) readPointer(offset int) uint64 {
+	if t.pointerSize == 4 {
+		return uint64(binary.LittleEndian.Uint32(t.buffer[offset:]))
+	}
+	return binary.LittleEndian.Uint64(t.buffer[offset:])
+}
+
+// alignUp rounds n up to a multiple of align
+func alignUp(n, align int) int {
+	return (n + align - 1) / align * align
+}
@@ -0,0 +1,391 @@
+package handle
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// tableFixtureEntries are encoded into every layout by encodeHandleTable.
+// The legacy layout keeps only 16 bits of process IDs and handle values,
+// 8 bits of type indexes and attributes, and the pointer size of objects.
+var tableFixtureEntries = []SystemHandleEntry{
+	{
+		Object:                0xffffa0012345c080,
+		ProcessID:             4,
+		HandleValue:           0x4,
+		GrantedAccess:         0x1fffff,
+		CreatorBackTraceIndex: 0,
+		ObjectTypeIndex:       7,
+		HandleAttributes:      0x2,
+	},
+	{
+		Object:                0xffffa0019abcd0f0,
+		ProcessID:             0x12345,
+		HandleValue:           0x1a2b4,
+		GrantedAccess:         0x1f0003,
+		CreatorBackTraceIndex: 0x1234,
+		ObjectTypeIndex:       0x123,
+		HandleAttributes:      0x8001,
+	},
+}
+
+// encodeHandleTable writes entries in the given layout and pointer size,
+// field by field at the offsets documented for the native structures
+func encodeHandleTable(layout TableLayout, pointerSize int, entries []SystemHandleEntry) []byte {
+	putPointer := func(b []byte, v uint64) {
+		if pointerSize == 4 {
+			binary.LittleEndian.PutUint32(b, uint32(v))
+		} else {
+			binary.LittleEndian.PutUint64(b, v)
+		}
+	}
+
+	if layout == LayoutLegacy {
+		entrySize := 8 + pointerSize + 4
+		if pointerSize == 8 {
+			entrySize = 24
+		}
+		buffer := make([]byte, pointerSize+len(entries)*entrySize)
+		binary.LittleEndian.PutUint32(buffer, uint32(len(entries)))
+		for i, e := range entries {
+			b := buffer[pointerSize+i*entrySize:]
+			binary.LittleEndian.PutUint16(b[0:], uint16(e.ProcessID))
+			binary.LittleEndian.PutUint16(b[2:], e.CreatorBackTraceIndex)
+			b[4] = byte(e.ObjectTypeIndex)
+			b[5] = byte(e.HandleAttributes)
+			binary.LittleEndian.PutUint16(b[6:], uint16(e.HandleValue))
+			putPointer(b[8:], e.Object)
+			binary.LittleEndian.PutUint32(b[8+pointerSize:], e.GrantedAccess)
+		}
+		return buffer
+	}
+
+	entrySize := 3*pointerSize + 16
+	buffer := make([]byte, 2*pointerSize+len(entries)*entrySize)
+	putPointer(buffer, uint64(len(entries)))
+	for i, e := range entries {
+		b := buffer[2*pointerSize+i*entrySize:]
+		putPointer(b[0:], e.Object)
+		putPointer(b[pointerSize:], uint64(e.ProcessID))
+		putPointer(b[2*pointerSize:], e.HandleValue)
+		binary.LittleEndian.PutUint32(b[3*pointerSize:], e.GrantedAccess)
+		binary.LittleEndian.PutUint16(b[3*pointerSize+4:], e.CreatorBackTraceIndex)
+		binary.LittleEndian.PutUint16(b[3*pointerSize+6:], e.ObjectTypeIndex)
+		binary.LittleEndian.PutUint32(b[3*pointerSize+8:], e.HandleAttributes)
+	}
+	return buffer
+}
+
+// truncatedEntry returns e as stored in the given layout and pointer size
+func truncatedEntry(e SystemHandleEntry, layout TableLayout, pointerSize int) SystemHandleEntry {
+	if pointerSize == 4 {
+		e.Object = uint64(uint32(e.Object))
+		e.HandleValue = uint64(uint32(e.HandleValue))
+	}
+	if layout == LayoutLegacy {
+		e.ProcessID = uint32(uint16(e.ProcessID))
+		e.HandleValue = uint64(uint16(e.HandleValue))
+		e.ObjectTypeIndex = uint16(uint8(e.ObjectTypeIndex))
+		e.HandleAttributes = uint32(uint8(e.HandleAttributes))
+	}
+	return e
+}
+
+func TestParseHandleTable(t *testing.T) {
+	tests := []struct {
+		name        string
+		layout      TableLayout
+		pointerSize int
+		size        int
+	}{
+		{name: "extended 64-bit", layout: LayoutExtended, pointerSize: 8, size: 16 + 2*40},
+		{name: "extended 32-bit", layout: LayoutExtended, pointerSize: 4, size: 8 + 2*28},
+		{name: "legacy 64-bit", layout: LayoutLegacy, pointerSize: 8, size: 8 + 2*24},
+		{name: "legacy 32-bit", layout: LayoutLegacy, pointerSize: 4, size: 4 + 2*16},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buffer := encodeHandleTable(tt.layout, tt.pointerSize, tableFixtureEntries)
+			if len(buffer) != tt.size {
+				t.Fatalf("fixture is %d bytes, want %d", len(buffer), tt.size)
+			}
+
+			table, err := ParseHandleTable(buffer, tt.layout, tt.pointerSize)
+			if err != nil {
+				t.Fatalf("ParseHandleTable() error = %v", err)
+			}
+			if table.Layout() != tt.layout {
+				t.Errorf("Layout() = %v, want %v", table.Layout(), tt.layout)
+			}
+			if table.Len() != len(tableFixtureEntries) {
+				t.Fatalf("Len() = %d, want %d", table.Len(), len(tableFixtureEntries))
+			}
+			for i, want := range tableFixtureEntries {
+				want = truncatedEntry(want, tt.layout, tt.pointerSize)
+				if got := table.Entry(i); got != want {
+					t.Errorf("Entry(%d) = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseHandleTableRawFixtures(t *testing.T) {
+	tests := []struct {
+		name        string
+		layout      TableLayout
+		pointerSize int
+		buffer      []byte
+		want        SystemHandleEntry
+	}{
+		{
+			name:        "extended 64-bit",
+			layout:      LayoutExtended,
+			pointerSize: 8,
+			buffer: []byte{
+				0x01, 0, 0, 0, 0, 0, 0, 0, // NumberOfHandles
+				0, 0, 0, 0, 0, 0, 0, 0, // Reserved
+				0x80, 0xc0, 0x45, 0x23, 0x01, 0xa0, 0xff, 0xff, // Object
+				0x34, 0x12, 0, 0, 0, 0, 0, 0, // UniqueProcessId
+				0x44, 0x00, 0, 0, 0, 0, 0, 0, // HandleValue
+				0x03, 0x00, 0x1f, 0x00, // GrantedAccess
+				0x00, 0x00, // CreatorBackTraceIndex
+				0x10, 0x00, // ObjectTypeIndex
+				0x00, 0x00, 0x00, 0x00, // HandleAttributes
+				0, 0, 0, 0, // Reserved
+			},
+			want: SystemHandleEntry{
+				Object:          0xffffa0012345c080,
+				ProcessID:       0x1234,
+				HandleValue:     0x44,
+				GrantedAccess:   0x1f0003,
+				ObjectTypeIndex: 0x10,
+			},
+		},
+		{
+			name:        "legacy 32-bit",
+			layout:      LayoutLegacy,
+			pointerSize: 4,
+			buffer: []byte{
+				0x01, 0, 0, 0, // NumberOfHandles
+				0x34, 0x12, // UniqueProcessId
+				0x00, 0x00, // CreatorBackTraceIndex
+				0x10,       // ObjectTypeIndex
+				0x02,       // HandleAttributes
+				0x44, 0x00, // HandleValue
+				0x80, 0xc0, 0x45, 0x83, // Object
+				0x03, 0x00, 0x1f, 0x00, // GrantedAccess
+			},
+			want: SystemHandleEntry{
+				Object:           0x8345c080,
+				ProcessID:        0x1234,
+				HandleValue:      0x44,
+				GrantedAccess:    0x1f0003,
+				ObjectTypeIndex:  0x10,
+				HandleAttributes: 0x2,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := ParseHandleTable(tt.buffer, tt.layout, tt.pointerSize)
+			if err != nil {
+				t.Fatalf("ParseHandleTable() error = %v", err)
+			}
+			if table.Len() != 1 {
+				t.Fatalf("Len() = %d, want 1", table.Len())
+			}
+			if got := table.Entry(0); got != tt.want {
+				t.Errorf("Entry(0) = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHandleTableErrors(t *testing.T) {
+	valid := encodeHandleTable(LayoutExtended, 8, tableFixtureEntries)
+	oversized := append([]byte(nil), valid...)
+	binary.LittleEndian.PutUint64(oversized, 1<<62)
+
+	tests := []struct {
+		name        string
+		buffer      []byte
+		layout      TableLayout
+		pointerSize int
+	}{
+		{name: "empty buffer", buffer: nil, layout: LayoutExtended, pointerSize: 8},
+		{name: "short header", buffer: valid[:7], layout: LayoutExtended, pointerSize: 8},
+		{name: "truncated entry", buffer: valid[:len(valid)-1], layout: LayoutExtended, pointerSize: 8},
+		{name: "oversized count", buffer: oversized, layout: LayoutExtended, pointerSize: 8},
+		{name: "truncated legacy entry", buffer: encodeHandleTable(LayoutLegacy, 4, tableFixtureEntries)[:20], layout: LayoutLegacy, pointerSize: 4},
+		{name: "unsupported pointer size", buffer: valid, layout: LayoutExtended, pointerSize: 2},
+		{name: "unknown layout", buffer: valid, layout: TableLayout(9), pointerSize: 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseHandleTable(tt.buffer, tt.layout, tt.pointerSize)
+			if !errors.Is(err, ErrInvalidTable) {
+				t.Errorf("ParseHandleTable() error = %v, want %v", err, ErrInvalidTable)
+			}
+		})
+	}
+}
+
+func TestHandleTableByProcess(t *testing.T) {
+	entries := []SystemHandleEntry{
+		{ProcessID: 0x10, HandleValue: 0x4},
+		{ProcessID: 0x20, HandleValue: 0x4},
+		{ProcessID: 0x10, HandleValue: 0x8},
+		{ProcessID: 0x30, HandleValue: 0xc},
+		{ProcessID: 0x10010, HandleValue: 0x10},
+		{ProcessID: 0x20050, HandleValue: 0x14},
+		{ProcessID: 0x50, HandleValue: 0x18},
+	}
+	requested := []uint32{0x10, 0x30, 0x40, 0x50, 0x10050}
+
+	tests := []struct {
+		name      string
+		layout    TableLayout
+		want      map[uint32][]int
+		ambiguous []uint32
+	}{
+		{
+			name:   "extended",
+			layout: LayoutExtended,
+			want:   map[uint32][]int{0x10: {0, 2}, 0x30: {3}, 0x40: nil, 0x50: {6}, 0x10050: nil},
+		},
+		{
+			// 0x10010 is listed as 0x10 and 0x20050 as 0x50, which 0x10050 shares
+			name:      "legacy",
+			layout:    LayoutLegacy,
+			want:      map[uint32][]int{0x10: {0, 2, 4}, 0x30: {3}, 0x40: nil},
+			ambiguous: []uint32{0x50, 0x10050},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := ParseHandleTable(encodeHandleTable(tt.layout, 8, entries), tt.layout, 8)
+			if err != nil {
+				t.Fatalf("ParseHandleTable() error = %v", err)
+			}
+
+			got, ambiguous := table.ByProcess(requested)
+			if !slices.Equal(ambiguous, tt.ambiguous) {
+				t.Errorf("ByProcess() ambiguous = %#x, want %#x", ambiguous, tt.ambiguous)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ByProcess() = %v, want entries of %d processes", got, len(tt.want))
+			}
+			for pid, indexes := range tt.want {
+				var want []SystemHandleEntry
+				for _, i := range indexes {
+					want = append(want, truncatedEntry(entries[i], tt.layout, 8))
+				}
+				if list, ok := got[pid]; !ok || !slices.Equal(list, want) {
+					t.Errorf("ByProcess()[%#x] = %v, want %v", pid, list, want)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkHandleTableByProcess compares one snapshot shared by all the
+// processes of a cycle, which copies and parses a multi-megabyte table once,
+// against one snapshot per process, which copies and parses it every time
+func BenchmarkHandleTableByProcess(b *testing.B) {
+	const (
+		processCount = 8
+		handleCount  = 100_000
+		systemPIDs   = 500
+	)
+
+	// A busy system: handles spread over many processes, 40 bytes each
+	entries := make([]SystemHandleEntry, handleCount)
+	for i := range entries {
+		entries[i] = SystemHandleEntry{
+			Object:          0xffffa00100000000 + uint64(i)*0x40,
+			ProcessID:       uint32(4 * (i%systemPIDs + 1)),
+			HandleValue:     uint64(4 * (i/systemPIDs + 1)),
+			GrantedAccess:   0x1f0003,
+			ObjectTypeIndex: uint16(i%64 + 1),
+		}
+	}
+	system := encodeHandleTable(LayoutExtended, 8, entries)
+	pids := make([]uint32, processCount)
+	for i := range pids {
+		pids[i] = uint32(4 * (i + 1))
+	}
+
+	// snapshot stands in for the system handle query, which copies the table
+	snapshot := func(b *testing.B, processIDs []uint32) map[uint32][]SystemHandleEntry {
+		table, err := ParseHandleTable(bytes.Clone(system), LayoutExtended, 8)
+		if err != nil {
+			b.Fatalf("ParseHandleTable() error = %v", err)
+		}
+		targets, _ := table.ByProcess(processIDs)
+		return targets
+	}
+
+	b.Run("shared", func(b *testing.B) {
+		b.SetBytes(int64(len(system)))
+		for b.Loop() {
+			snapshot(b, pids)
+		}
+	})
+
+	b.Run("per process", func(b *testing.B) {
+		b.SetBytes(int64(len(system)))
+		for b.Loop() {
+			for _, pid := range pids {
+				snapshot(b, []uint32{pid})
+			}
+		}
+	})
+}
+
+func FuzzParseHandleTable(f *testing.F) {
+	for _, layout := range []TableLayout{LayoutExtended, LayoutLegacy} {
+		for _, pointerSize := range []int{4, 8} {
+			buffer := encodeHandleTable(layout, pointerSize, tableFixtureEntries)
+			f.Add(buffer, uint8(layout), uint8(pointerSize))
+			f.Add(buffer[:len(buffer)-1], uint8(layout), uint8(pointerSize))
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, buffer []byte, layoutByte, pointerByte uint8) {
+		layout := TableLayout(layoutByte % 3)
+		pointerSize := int(pointerByte % 10)
+
+		table, err := ParseHandleTable(buffer, layout, pointerSize)
+		if err != nil {
+			if !errors.Is(err, ErrInvalidTable) {
+				t.Fatalf("ParseHandleTable() error = %v, want %v", err, ErrInvalidTable)
+			}
+			return
+		}
+
+		// The count must have been checked against the buffer size
+		var headerSize, entrySize int
+		var count uint64
+		if layout == LayoutLegacy {
+			headerSize, entrySize = pointerSize, alignUp(8+pointerSize+4, pointerSize)
+			count = uint64(binary.LittleEndian.Uint32(buffer))
+		} else {
+			headerSize, entrySize = 2*pointerSize, 3*pointerSize+16
+			count = table.readPointer(0)
+		}
+		if count != uint64(table.Len()) || headerSize+table.Len()*entrySize > len(buffer) {
+			t.Fatalf("accepted %d entries in %d bytes", count, len(buffer))
+		}
+
+		// Decoding every accepted entry must not panic
+		table.Entries()
+	})
+}
@@ -0,0 +1,32 @@
+//go:build windows
+
+package handle
+
+import "sync"
+
+// typeCache maps object type indexes to type names. The indexes do not
+// change while the system runs, so each one only needs to be resolved once.
+type typeCache struct {
+	names map[uint16]string
+	mu    sync.RWMutex
+}
+
+// newTypeCache creates an empty type cache
+func newTypeCache() *typeCache {
+	return &typeCache{names: make(map[uint16]string)}
+}
+
+// lookup returns the type name of an index, if it was learned already
+func (c *typeCache) lookup(index uint16) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	name, ok := c.names[index]
+	return name, ok
+}
+
+// learn records the type name of an index
+func (c *typeCache) learn(index uint16, name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.names[index] = name
+}
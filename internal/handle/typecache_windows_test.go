@@ -0,0 +1,73 @@
+//go:build windows
+
+package handle
+
+import (
+	"testing"
+
+	"golang.org/x/sys/windows"
+)
+
+// BenchmarkInspectHandlesTypeCache compares resolving the types of a
+// synthetic handle table with an empty type cache, where every handle is
+// duplicated and queried, against a cache that knows every type index
+func BenchmarkInspectHandlesTypeCache(b *testing.B) {
+	const handleCount = 256
+
+	// Real event handles of this process, each with its own type index so
+	// that an empty cache has to resolve every one of them
+	pid := windows.GetCurrentProcessId()
+	entries := make([]SystemHandleEntry, handleCount)
+	for i := range entries {
+		h, err := windows.CreateEvent(nil, 0, 0, nil)
+		if err != nil {
+			b.Fatalf("CreateEvent failed: %v", err)
+		}
+		b.Cleanup(func() {
+			_ = windows.CloseHandle(h)
+		})
+		entries[i] = SystemHandleEntry{
+			ProcessID:       pid,
+			HandleValue:     uint64(h),
+			ObjectTypeIndex: uint16(i + 1),
+			GrantedAccess:   windows.EVENT_ALL_ACCESS,
+		}
+	}
+
+	table, err := ParseHandleTable(encodeHandleTable(LayoutExtended, pointerSize, entries), LayoutExtended, pointerSize)
+	if err != nil {
+		b.Fatalf("ParseHandleTable() error = %v", err)
+	}
+	handles := make([]handleEntry, table.Len())
+	for i := range handles {
+		entry := table.Entry(i)
+		handles[i] = handleEntry{
+			value:         uintptr(entry.HandleValue),
+			typeIndex:     entry.ObjectTypeIndex,
+			grantedAccess: entry.GrantedAccess,
+		}
+	}
+
+	resolver := NewNameResolver(DefaultNameTimeout, DefaultMaxStuckWorkers)
+	named := map[string]bool{}
+
+	b.Run("uncached", func(b *testing.B) {
+		for b.Loop() {
+			inspectHandles(NewSnapshot(), named, resolver, newTypeCache(), pid, handles)
+		}
+	})
+
+	b.Run("cached", func(b *testing.B) {
+		types := newTypeCache()
+		inspectHandles(NewSnapshot(), named, resolver, types, pid, handles)
+		for index := uint16(1); index <= handleCount; index++ {
+			if _, ok := types.lookup(index); !ok {
+				b.Fatalf("type index %d was not learned", index)
+			}
+		}
+
+		for b.Loop() {
+			inspectHandles(NewSnapshot(), named, resolver, types, pid, handles)
+		}
+	})
+}
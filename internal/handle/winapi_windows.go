@@ -1,3 +1,5 @@
+//go:build windows
+
 package handle
 
 import (
@@ -8,12 +10,17 @@ import (
 )
 
 const (
-	// SystemHandleInformation is the information class for querying system handles (32-bit)
+	// SystemHandleInformation is the legacy information class for querying system handles,
+	// which stores process IDs and handle values in 16 bits
 	SystemHandleInformation = 16
 
-	// SystemExtendedHandleInformation is the information class for querying system handles (64-bit)
+	// SystemExtendedHandleInformation is the information class for querying system handles
+	// with full-width process IDs and handle values, on 32-bit and 64-bit Windows
 	SystemExtendedHandleInformation = 64
 
+	// ObjectBasicInformation is the information class for querying the granted access of a handle
+	ObjectBasicInformation = 0
+
 	// ObjectNameInformation is the information class for querying object names
 	ObjectNameInformation = 1
 
@@ -23,6 +30,18 @@ const (
 	// StatusInfoLengthMismatch indicates buffer is too small
 	StatusInfoLengthMismatch = 0xC0000004
 
+	// StatusInvalidInfoClass indicates the information class is not supported
+	StatusInvalidInfoClass = 0xC0000003
+
+	// StatusAccessDenied indicates the object exists but cannot be opened
+	StatusAccessDenied = 0xC0000022
+
+	// StatusObjectNameNotFound indicates no object has the requested name
+	StatusObjectNameNotFound = 0xC0000034
+
+	// StatusObjectPathNotFound indicates a directory of the requested name does not exist
+	StatusObjectPathNotFound = 0xC000003A
+
 	// DuplicateCloseSource closes the source handle
 	DuplicateCloseSource = 0x00000001
 
@@ -35,45 +54,11 @@ var (
 	procNtQuerySystemInfo = ntdll.NewProc("NtQuerySystemInformation")
 	procNtQueryObject     = ntdll.NewProc("NtQueryObject")
 	procNtDuplicateObject = ntdll.NewProc("NtDuplicateObject")
+	procNtOpenEvent       = ntdll.NewProc("NtOpenEvent")
+	procNtOpenMutant      = ntdll.NewProc("NtOpenMutant")
+	procNtOpenSemaphore   = ntdll.NewProc("NtOpenSemaphore")
 )
 
-// SystemHandleTableEntryInfo represents a single handle entry in the system (32-bit)
-type SystemHandleTableEntryInfo struct {
-	UniqueProcessID       uint16
-	CreatorBackTraceIndex uint16
-	ObjectTypeIndex       byte
-	HandleAttributes      byte
-	HandleValue           uint16
-	Object                uintptr
-	GrantedAccess         uint32
-}
-
-// SystemHandleInformationEx represents system handle information (32-bit)
-type SystemHandleInformationEx struct {
-	NumberOfHandles uintptr
-	Reserved        uintptr
-	Handles         [1]SystemHandleTableEntryInfo
-}
-
-// SystemHandleTableEntryInfoEx represents a single handle entry in the system (64-bit)
-type SystemHandleTableEntryInfoEx struct {
-	Object                uintptr
-	UniqueProcessID       uintptr
-	HandleValue           uintptr
-	GrantedAccess         uint32
-	CreatorBackTraceIndex uint16
-	ObjectTypeIndex       uint16
-	HandleAttributes      uint32
-	Reserved              uint32
-}
-
-// SystemExtendedHandleInformationEx represents system handle information (64-bit)
-type SystemExtendedHandleInformationEx struct {
-	NumberOfHandles uintptr
-	Reserved        uintptr
-	Handles         [1]SystemHandleTableEntryInfoEx
-}
-
 // UnicodeString represents a Windows UNICODE_STRING
 type UnicodeString struct {
 	Length        uint16
@@ -91,6 +76,16 @@ type ObjectTypeInfo struct {
 	TypeName UnicodeString
 }
 
+// ObjectBasicInfo represents OBJECT_BASIC_INFORMATION, which must be queried
+// with its exact size of 56 bytes
+type ObjectBasicInfo struct {
+	Attributes    uint32
+	GrantedAccess uint32
+	HandleCount   uint32
+	PointerCount  uint32
+	Reserved      [10]uint32
+}
+
 // ntQuerySystemInformation queries system information
 func ntQuerySystemInformation(
 	systemInformationClass uint32,
@@ -159,6 +154,26 @@ func ntDuplicateObject(
 	return
 }
 
+// ntOpenObject opens a named object with one of the NtOpenEvent,
+// NtOpenMutant or NtOpenSemaphore procedures, which share their signature
+func ntOpenObject(
+	proc *windows.LazyProc,
+	handle *windows.Handle,
+	desiredAccess uint32,
+	objectAttributes *windows.OBJECT_ATTRIBUTES,
+) (ntstatus error) {
+	r0, _, _ := syscall.SyscallN(
+		proc.Addr(),
+		uintptr(unsafe.Pointer(handle)),
+		uintptr(desiredAccess),
+		uintptr(unsafe.Pointer(objectAttributes)),
+	)
+	if r0 != 0 {
+		ntstatus = syscall.Errno(r0)
+	}
+	return
+}
+
 // getUnicodeString converts a UnicodeString to a Go string
 func getUnicodeString(us *UnicodeString) string {
 	if us.Buffer == nil || us.Length == 0 {
@@ -0,0 +1,51 @@
+//go:build !windows
+
+package process
+
+import (
+	"time"
+)
+
+// systemBackend reports ErrUnsupported for every operation on non-Windows systems
+type systemBackend struct{}
+
+// NewSystemBackend returns the Backend for the running operating system
+func NewSystemBackend() Backend {
+	return systemBackend{}
+}
+
+func (systemBackend) FindProcessesByName(string) ([]ProcessInfo, error) {
+	return nil, ErrUnsupported
+}
+
+func (systemBackend) FindProcessesByNames([]string) ([]ProcessInfo, error) {
+	return nil, ErrUnsupported
+}
+
+func (systemBackend) ReadProcessDetails(info ProcessInfo) ProcessInfo {
+	return info
+}
+
+func (systemBackend) GetProcessCreationTime(uint32) (time.Time, error) {
+	return time.Time{}, ErrUnsupported
+}
+
+func (systemBackend) GetProcessUptime(uint32) (time.Duration, error) {
+	return 0, ErrUnsupported
+}
+
+func (systemBackend) GetProcessExecutablePath(uint32) (string, error) {
+	return "", ErrUnsupported
+}
+
+func (systemBackend) GetProcessCommandLine(uint32) ([]string, error) {
+	return nil, ErrUnsupported
+}
+
+func (systemBackend) KillProcess(uint32, time.Time) error {
+	return ErrUnsupported
+}
+
+func (systemBackend) LaunchProcess(string, LaunchOptions) (uint32, error) {
+	return 0, ErrUnsupported
+}
@@ -0,0 +1,51 @@
+//go:build windows
+
+package process
+
+import (
+	"time"
+)
+
+// systemBackend implements Backend with the Windows API
+type systemBackend struct{}
+
+// NewSystemBackend returns the Backend for the running operating system
+func NewSystemBackend() Backend {
+	return systemBackend{}
+}
+
+func (systemBackend) FindProcessesByName(name string) ([]ProcessInfo, error) {
+	return FindProcessesByName(name)
+}
+
+func (systemBackend) FindProcessesByNames(names []string) ([]ProcessInfo, error) {
+	return FindProcessesByNames(names)
+}
+
+func (systemBackend) ReadProcessDetails(info ProcessInfo) ProcessInfo {
+	return ReadProcessDetails(info)
+}
+
+func (systemBackend) GetProcessCreationTime(pid uint32) (time.Time, error) {
+	return GetProcessCreationTime(pid)
+}
+
+func (systemBackend) GetProcessUptime(pid uint32) (time.Duration, error) {
+	return GetProcessUptime(pid)
+}
+
+func (systemBackend) GetProcessExecutablePath(pid uint32) (string, error) {
+	return GetProcessExecutablePath(pid)
+}
+
+func (systemBackend) GetProcessCommandLine(pid uint32) ([]string, error) {
+	return GetProcessCommandLine(pid)
+}
+
+func (systemBackend) KillProcess(pid uint32, created time.Time) error {
+	return KillProcess(pid, created)
+}
+
+func (systemBackend) LaunchProcess(executablePath string, opts LaunchOptions) (uint32, error) {
+	return LaunchProcess(executablePath, opts)
+}
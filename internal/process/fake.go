@@ -0,0 +1,345 @@
+package process
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Ensure Fake implements Backend
+var _ Backend = (*Fake)(nil)
+
+// FakeProcess describes a process simulated by Fake
+type FakeProcess struct {
+	PID       uint32
+	ParentPID uint32
+	Threads   uint32
+	Name      string
+	Path      string
+	Args      []string
+	StartTime time.Time
+}
+
+// FakeLaunch records a LaunchProcess call made on Fake
+type FakeLaunch struct {
+	Path    string
+	Options LaunchOptions
+}
+
+// Fake is a scriptable in-memory Backend for tests.
+// It simulates a process table with its own clock, records every kill and
+// launch, and lets callers inject failures for individual operations.
+type Fake struct {
+	now       time.Time
+	processes []FakeProcess
+	nextPID   uint32
+
+	// Recorded operations
+	killed      []uint32
+	launched    []FakeLaunch
+	detailReads int
+
+	// Injected failures
+	findErr   error
+	launchErr error
+	killErrs  map[uint32]error
+
+	mu sync.Mutex
+}
+
+// NewFake creates an empty fake process table whose clock starts at now
+func NewFake(now time.Time) *Fake {
+	return &Fake{
+		now:      now,
+		nextPID:  1000,
+		killErrs: make(map[uint32]error),
+	}
+}
+
+// AddProcess adds a process to the table.
+// A zero PID is replaced by the next free PID and a zero StartTime by the
+// current fake time. The PID of the added process is returned.
+func (f *Fake) AddProcess(p FakeProcess) uint32 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.addProcessLocked(p)
+}
+
+// addProcessLocked adds a process to the table (caller must hold f.mu)
+func (f *Fake) addProcessLocked(p FakeProcess) uint32 {
+	if p.PID == 0 {
+		p.PID = f.nextPID
+		f.nextPID += 4
+	}
+	if p.StartTime.IsZero() {
+		p.StartTime = f.now
+	}
+	f.processes = append(f.processes, p)
+	return p.PID
+}
+
+// RemoveProcess removes a process from the table, as if it had exited
+func (f *Fake) RemoveProcess(pid uint32) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.removeProcessLocked(pid)
+}
+
+// removeProcessLocked removes a process from the table (caller must hold f.mu)
+func (f *Fake) removeProcessLocked(pid uint32) {
+	for i, p := range f.processes {
+		if p.PID == pid {
+			f.processes = append(f.processes[:i], f.processes[i+1:]...)
+			return
+		}
+	}
+}
+
+// Processes returns a copy of the current process table
+func (f *Fake) Processes() []FakeProcess {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FakeProcess(nil), f.processes...)
+}
+
+// Advance moves the fake clock forward
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+// Now returns the current fake time
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Killed returns the PIDs terminated so far, in order
+func (f *Fake) Killed() []uint32 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]uint32(nil), f.killed...)
+}
+
+// Launched returns the executable paths launched so far, in order
+func (f *Fake) Launched() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	paths := make([]string, 0, len(f.launched))
+	for _, l := range f.launched {
+		paths = append(paths, l.Path)
+	}
+	return paths
+}
+
+// Launches returns every launch recorded so far with its options, in order
+func (f *Fake) Launches() []FakeLaunch {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FakeLaunch(nil), f.launched...)
+}
+
+// SetFindError makes FindProcessesByName and FindProcessesByNames fail with err (nil clears it)
+func (f *Fake) SetFindError(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.findErr = err
+}
+
+// SetLaunchError makes LaunchProcess fail with err (nil clears it)
+func (f *Fake) SetLaunchError(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.launchErr = err
+}
+
+// SetKillError makes terminating the given PID fail with err (nil clears it)
+func (f *Fake) SetKillError(pid uint32, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err == nil {
+		delete(f.killErrs, pid)
+		return
+	}
+	f.killErrs[pid] = err
+}
+
+// FindProcessesByName finds all processes with the given name (case-insensitive)
+func (f *Fake) FindProcessesByName(name string) ([]ProcessInfo, error) {
+	return f.FindProcessesByNames([]string{name})
+}
+
+// FindProcessesByNames finds all processes with any of the given names
+// (case-insensitive). Like the system backend, it fills in only the PID,
+// name, parent PID and thread count.
+func (f *Fake) FindProcessesByNames(names []string) ([]ProcessInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.findErr != nil {
+		return nil, f.findErr
+	}
+
+	var processes []ProcessInfo
+	for _, p := range f.processes {
+		if matchesName(p.Name, names) {
+			processes = append(processes, ProcessInfo{
+				PID:       p.PID,
+				Name:      p.Name,
+				ParentPID: p.ParentPID,
+				Threads:   p.Threads,
+			})
+		}
+	}
+	return processes, nil
+}
+
+// ReadProcessDetails fills in the command line, Path and StartTime of a
+// process and counts the call. An empty Path is left out, like an
+// access-denied query would be.
+func (f *Fake) ReadProcessDetails(info ProcessInfo) ProcessInfo {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, ok := f.findLocked(info.PID)
+	if !ok {
+		return info
+	}
+	f.detailReads++
+	info.CommandLine = commandLine(p)
+	info.Path = p.Path
+	info.CreationTime = p.StartTime
+	return info
+}
+
+// DetailReads returns how many times ReadProcessDetails found a process
+func (f *Fake) DetailReads() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.detailReads
+}
+
+// GetProcessCreationTime returns the StartTime of a process
+func (f *Fake) GetProcessCreationTime(pid uint32) (time.Time, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, ok := f.findLocked(pid)
+	if !ok {
+		return time.Time{}, fmt.Errorf("failed to open process %d: not found", pid)
+	}
+	return p.StartTime, nil
+}
+
+// GetProcessUptime returns how long a process has been running on the fake clock
+func (f *Fake) GetProcessUptime(pid uint32) (time.Duration, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, ok := f.findLocked(pid)
+	if !ok {
+		return 0, fmt.Errorf("failed to open process %d: not found", pid)
+	}
+	return f.now.Sub(p.StartTime), nil
+}
+
+// GetProcessExecutablePath returns the path of a process.
+// An empty path is reported as an error, like an access-denied query would be.
+func (f *Fake) GetProcessExecutablePath(pid uint32) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, ok := f.findLocked(pid)
+	if !ok {
+		return "", fmt.Errorf("failed to open process %d: not found", pid)
+	}
+	if p.Path == "" {
+		return "", fmt.Errorf("QueryFullProcessImageName failed for PID %d: access denied", pid)
+	}
+	return p.Path, nil
+}
+
+// GetProcessCommandLine returns the path, or the name when the path is
+// empty, followed by the Args of a process
+func (f *Fake) GetProcessCommandLine(pid uint32) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, ok := f.findLocked(pid)
+	if !ok {
+		return nil, fmt.Errorf("failed to open process %d: not found", pid)
+	}
+	return commandLine(p), nil
+}
+
+// commandLine returns the path, or the name when the path is empty,
+// followed by the Args of a process
+func commandLine(p FakeProcess) []string {
+	program := p.Path
+	if program == "" {
+		program = p.Name
+	}
+	return append([]string{program}, p.Args...)
+}
+
+// KillProcess terminates a process unless created is non-zero and differs
+// from its StartTime
+func (f *Fake) KillProcess(pid uint32, created time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, ok := f.findLocked(pid)
+	if !ok {
+		return fmt.Errorf("failed to open process %d: not found", pid)
+	}
+	if !created.IsZero() && !p.StartTime.Equal(created) {
+		return fmt.Errorf("%w: PID %d", ErrProcessReused, pid)
+	}
+	if err := f.killErrs[pid]; err != nil {
+		return fmt.Errorf("failed to terminate process %d: %w", pid, err)
+	}
+
+	f.removeProcessLocked(pid)
+	f.killed = append(f.killed, pid)
+	return nil
+}
+
+// LaunchProcess records the launch and adds a new process for the executable
+func (f *Fake) LaunchProcess(executablePath string, opts LaunchOptions) (uint32, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.launched = append(f.launched, FakeLaunch{Path: executablePath, Options: opts})
+	if f.launchErr != nil {
+		return 0, fmt.Errorf("failed to launch %s: %w", executablePath, f.launchErr)
+	}
+
+	pid := f.addProcessLocked(FakeProcess{
+		Name: baseName(executablePath),
+		Path: executablePath,
+		Args: opts.Args,
+	})
+	return pid, nil
+}
+
+// findLocked looks up a process by PID (caller must hold f.mu)
+func (f *Fake) findLocked(pid uint32) (FakeProcess, bool) {
+	for _, p := range f.processes {
+		if p.PID == pid {
+			return p, true
+		}
+	}
+	return FakeProcess{}, false
+}
+
+// baseName returns the file name of a Windows or slash-separated path
+func baseName(path string) string {
+	if i := strings.LastIndexAny(path, `\/`); i >= 0 {
+		return path[i+1:]
+	}
+	return path
+}
@@ -0,0 +1,162 @@
+//go:build windows
+
+package process
+
+import (
+	"fmt"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// FindProcessesByName finds all processes with the given name
+func FindProcessesByName(name string) ([]ProcessInfo, error) {
+	return FindProcessesByNames([]string{name})
+}
+
+// FindProcessesByNames finds all processes with any of the given names
+// from a single process snapshot. Only the PID, name, parent PID and thread
+// count are filled in; ReadProcessDetails reads the rest.
+func FindProcessesByNames(names []string) ([]ProcessInfo, error) {
+	// Create a snapshot of all processes
+	snapshot, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return nil, fmt.Errorf("CreateToolhelp32Snapshot failed: %w", err)
+	}
+	defer func() {
+		_ = windows.CloseHandle(snapshot)
+	}()
+
+	var procEntry windows.ProcessEntry32
+	procEntry.Size = uint32(unsafe.Sizeof(procEntry))
+
+	// Get the first process
+	err = windows.Process32First(snapshot, &procEntry)
+	if err != nil {
+		return nil, fmt.Errorf("Process32First failed: %w", err)
+	}
+
+	var processes []ProcessInfo
+
+	// Iterate through all processes
+	for {
+		// Convert the process name from [260]uint16 to string
+		processName := syscall.UTF16ToString(procEntry.ExeFile[:])
+
+		// Check if the process name matches (case-insensitive)
+		if matchesName(processName, names) {
+			processes = append(processes, ProcessInfo{
+				PID:       procEntry.ProcessID,
+				Name:      processName,
+				ParentPID: procEntry.ParentProcessID,
+				Threads:   procEntry.Threads,
+			})
+		}
+
+		// Get the next process
+		err = windows.Process32Next(snapshot, &procEntry)
+		if err != nil {
+			// No more processes
+			break
+		}
+	}
+
+	return processes, nil
+}
+
+// GetProcessExecutablePath retrieves the full executable path of a process by PID
+func GetProcessExecutablePath(pid uint32) (string, error) {
+	// Open the process with QUERY_INFORMATION | VM_READ permissions
+	handle, err := windows.OpenProcess(windows.PROCESS_QUERY_INFORMATION|windows.PROCESS_VM_READ, false, pid)
+	if err != nil {
+		return "", fmt.Errorf("failed to open process %d: %w", pid, err)
+	}
+	defer func() {
+		_ = windows.CloseHandle(handle)
+	}()
+
+	return queryExecutablePath(handle, pid)
+}
+
+// queryExecutablePath reads the full executable path through an open process handle
+func queryExecutablePath(handle windows.Handle, pid uint32) (string, error) {
+	var exePath [windows.MAX_PATH]uint16
+	size := uint32(len(exePath))
+	err := windows.QueryFullProcessImageName(handle, 0, &exePath[0], &size)
+	if err != nil {
+		return "", fmt.Errorf("QueryFullProcessImageName failed for PID %d: %w", pid, err)
+	}
+
+	return syscall.UTF16ToString(exePath[:size]), nil
+}
+
+// GetProcessCommandLine returns the command line of a process split into
+// arguments; the first one is the program as it was started
+func GetProcessCommandLine(pid uint32) ([]string, error) {
+	handle, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open process %d: %w", pid, err)
+	}
+	defer func() {
+		_ = windows.CloseHandle(handle)
+	}()
+
+	return queryCommandLine(handle, pid)
+}
+
+// queryCommandLine reads the command line through an open process handle
+// and splits it into arguments
+func queryCommandLine(handle windows.Handle, pid uint32) ([]string, error) {
+	// The result is a UNICODE_STRING followed by its buffer; grow the
+	// buffer until the whole command line fits
+	buf := make([]byte, 512)
+	var err error
+	for {
+		var size uint32
+		err = windows.NtQueryInformationProcess(handle, windows.ProcessCommandLineInformation,
+			unsafe.Pointer(&buf[0]), uint32(len(buf)), &size)
+		if err != windows.STATUS_INFO_LENGTH_MISMATCH || int(size) <= len(buf) {
+			break
+		}
+		buf = make([]byte, size)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("NtQueryInformationProcess failed for PID %d: %w", pid, err)
+	}
+
+	commandLine := (*windows.NTUnicodeString)(unsafe.Pointer(&buf[0])).String()
+	args, err := windows.DecomposeCommandLine(commandLine)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the command line of PID %d: %w", pid, err)
+	}
+	return args, nil
+}
+
+// ReadProcessDetails returns info with the creation time, executable path
+// and command line of the process filled in, read through a single handle.
+// The limited query right is enough for all of them; details that cannot
+// be read are left empty.
+func ReadProcessDetails(info ProcessInfo) ProcessInfo {
+	handle, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, info.PID)
+	if err != nil {
+		// Access denied, or the process has just exited
+		return info
+	}
+	defer func() {
+		_ = windows.CloseHandle(handle)
+	}()
+
+	var creationTime, exitTime, kernelTime, userTime windows.Filetime
+	if err := windows.GetProcessTimes(handle, &creationTime, &exitTime, &kernelTime, &userTime); err == nil {
+		info.CreationTime = time.Unix(0, creationTime.Nanoseconds())
+	}
+	if path, err := queryExecutablePath(handle, info.PID); err == nil {
+		info.Path = path
+	}
+	if args, err := queryCommandLine(handle, info.PID); err == nil {
+		info.CommandLine = args
+	}
+	return info
+}
@@ -0,0 +1,42 @@
+//go:build windows
+
+package process
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// KillProcess terminates a process. A non-zero created time must equal the
+// creation time of the process, otherwise the PID was reused and
+// ErrProcessReused is returned without terminating anything.
+func KillProcess(pid uint32, created time.Time) error {
+	// Check the creation time through the same handle that terminates the
+	// process, so the PID cannot be reused in between
+	handle, err := windows.OpenProcess(windows.PROCESS_TERMINATE|windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return fmt.Errorf("failed to open process %d: %w", pid, err)
+	}
+	defer func() {
+		_ = windows.CloseHandle(handle)
+	}()
+
+	if !created.IsZero() {
+		var creationTime, exitTime, kernelTime, userTime windows.Filetime
+		err = windows.GetProcessTimes(handle, &creationTime, &exitTime, &kernelTime, &userTime)
+		if err != nil {
+			return fmt.Errorf("GetProcessTimes failed for PID %d: %w", pid, err)
+		}
+		if !time.Unix(0, creationTime.Nanoseconds()).Equal(created) {
+			return fmt.Errorf("%w: PID %d", ErrProcessReused, pid)
+		}
+	}
+
+	// Terminate the process with exit code 1
+	if err := windows.TerminateProcess(handle, 1); err != nil {
+		return fmt.Errorf("failed to terminate process %d: %w", pid, err)
+	}
+	return nil
+}
@@ -1,3 +1,5 @@
+//go:build windows
+
 package process
 
 import (
@@ -15,14 +17,19 @@ const (
 )
 
 // LaunchProcess starts a new process from the given executable path
-func LaunchProcess(executablePath string) error {
+// and returns its PID
+func LaunchProcess(executablePath string, opts LaunchOptions) (uint32, error) {
 	// Check if the file exists
 	if _, err := os.Stat(executablePath); os.IsNotExist(err) {
-		return fmt.Errorf("executable not found: %s", executablePath)
+		return 0, fmt.Errorf("executable not found: %s", executablePath)
 	}
 
 	// Create command to launch the process
-	cmd := exec.Command(executablePath)
+	cmd := exec.Command(executablePath, opts.Args...)
+	cmd.Dir = opts.Dir
+	if len(opts.Env) > 0 {
+		cmd.Env = append(os.Environ(), opts.Env...)
+	}
 
 	// Set Windows-specific process attributes to prevent window minimization
 	// and hide the child process window completely
@@ -33,8 +40,12 @@ func LaunchProcess(executablePath string) error {
 	// Start the process in detached mode (don't wait for it to finish)
 	err := cmd.Start()
 	if err != nil {
-		return fmt.Errorf("failed to launch %s: %w", executablePath, err)
+		return 0, fmt.Errorf("failed to launch %s: %w", executablePath, err)
 	}
 
-	return nil
+	// Release the process handle, the process keeps running on its own
+	pid := uint32(cmd.Process.Pid)
+	_ = cmd.Process.Release()
+
+	return pid, nil
 }
@@ -0,0 +1,114 @@
+// Package process provides utilities for Windows process management,
+// including process discovery, termination, and uptime tracking.
+package process
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrUnsupported is returned by the system backend on platforms other than Windows
+	ErrUnsupported = errors.New("process management is only supported on Windows")
+
+	// ErrNoProcess is returned when no process with the requested name is running
+	ErrNoProcess = errors.New("no process found with name")
+
+	// ErrProcessReused is returned by KillProcess when the PID now belongs to
+	// a process that was started after the expected one
+	ErrProcessReused = errors.New("process ID was reused by another process")
+)
+
+// ProcessInfo represents information about a process. Process lists only
+// fill in PID, Name, ParentPID and Threads; the details after them are
+// filled in by Backend.ReadProcessDetails and left empty when the process
+// denies access to them.
+type ProcessInfo struct {
+	PID  uint32
+	Name string
+
+	// ParentPID is the process that started this one; it may have exited since
+	ParentPID uint32
+
+	// Threads is the number of threads of the process
+	Threads uint32
+
+	// CommandLine is the command line split into arguments; the first one is
+	// the program as it was started
+	CommandLine []string
+
+	// Path is the full executable path
+	Path string
+
+	// CreationTime is when the process was started
+	CreationTime time.Time
+}
+
+// Args returns the command-line arguments after the program
+func (p ProcessInfo) Args() []string {
+	if len(p.CommandLine) < 2 {
+		return nil
+	}
+	return p.CommandLine[1:]
+}
+
+// matchesName reports whether name equals any of names, ignoring case
+func matchesName(name string, names []string) bool {
+	for _, n := range names {
+		if strings.EqualFold(name, n) {
+			return true
+		}
+	}
+	return false
+}
+
+// LaunchOptions controls how LaunchProcess starts a process
+type LaunchOptions struct {
+	// Args are the command-line arguments, not including the executable itself
+	Args []string
+
+	// Dir is the working directory; empty uses the current directory
+	Dir string
+
+	// Env holds extra "KEY=value" variables added to the current environment
+	Env []string
+}
+
+// Backend abstracts the operating system process operations used by the monitor,
+// so that the monitoring logic can run against a fake on any OS
+type Backend interface {
+	// FindProcessesByName finds all processes with the given name (case-insensitive)
+	FindProcessesByName(name string) ([]ProcessInfo, error)
+
+	// FindProcessesByNames finds all processes with any of the given names
+	// (case-insensitive) from a single process list
+	FindProcessesByNames(names []string) ([]ProcessInfo, error)
+
+	// ReadProcessDetails returns info with the command line, executable path
+	// and creation time of the process filled in; details that cannot be
+	// read are left empty
+	ReadProcessDetails(info ProcessInfo) ProcessInfo
+
+	// GetProcessCreationTime returns when a process was started
+	GetProcessCreationTime(pid uint32) (time.Time, error)
+
+	// GetProcessUptime returns how long a process has been running
+	GetProcessUptime(pid uint32) (time.Duration, error)
+
+	// GetProcessExecutablePath retrieves the full executable path of a process
+	GetProcessExecutablePath(pid uint32) (string, error)
+
+	// GetProcessCommandLine returns the command line of a process split into
+	// arguments; the first one is the program as it was started
+	GetProcessCommandLine(pid uint32) ([]string, error)
+
+	// KillProcess terminates a process. A non-zero created time must equal
+	// the creation time of the process, otherwise the PID was reused and
+	// ErrProcessReused is returned without terminating anything.
+	KillProcess(pid uint32, created time.Time) error
+
+	// LaunchProcess starts a new process from the given executable path
+	// and returns its PID
+	LaunchProcess(executablePath string, opts LaunchOptions) (uint32, error)
+}
@@ -0,0 +1,246 @@
+//go:build windows
+
+package process
+
+import (
+	"fmt"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	// processTraceQuery selects the start and stop events of all processes
+	processTraceQuery = "SELECT ProcessName FROM Win32_ProcessTrace"
+
+	// traceWaitTimeout bounds each wait for a trace event, so that Close is
+	// noticed while no process starts or stops
+	traceWaitTimeout = 250 * time.Millisecond
+
+	// WbemFlagReturnImmediately makes a WMI query return without waiting for its results
+	WbemFlagReturnImmediately = 0x10
+
+	// WbemFlagForwardOnly makes a WMI query return a forward-only enumerator
+	WbemFlagForwardOnly = 0x20
+
+	// RpcCAuthnWinNT selects NTLM authentication for a COM proxy
+	RpcCAuthnWinNT = 10
+
+	// RpcCAuthzNone selects no authorization for a COM proxy
+	RpcCAuthzNone = 0
+
+	// RpcCAuthnLevelCall authenticates every call of a COM proxy
+	RpcCAuthnLevelCall = 3
+
+	// RpcCImpLevelImpersonate lets WMI impersonate the caller
+	RpcCImpLevelImpersonate = 3
+
+	// EoacNone sets no extra capabilities for a COM proxy
+	EoacNone = 0
+
+	// VtBSTR is the VARIANT type of a BSTR string
+	VtBSTR = 8
+)
+
+// Vtable slots of the WMI interfaces used by the process trace
+const (
+	methodRelease               = 2
+	methodConnectServer         = 3  // IWbemLocator
+	methodExecNotificationQuery = 22 // IWbemServices
+	methodNext                  = 4  // IEnumWbemClassObject
+	methodGet                   = 4  // IWbemClassObject
+)
+
+var (
+	ole32                 = windows.NewLazySystemDLL("ole32.dll")
+	procCoCreateInstance  = ole32.NewProc("CoCreateInstance")
+	procCoSetProxyBlanket = ole32.NewProc("CoSetProxyBlanket")
+
+	oleaut32           = windows.NewLazySystemDLL("oleaut32.dll")
+	procSysAllocString = oleaut32.NewProc("SysAllocString")
+	procSysFreeString  = oleaut32.NewProc("SysFreeString")
+	procVariantClear   = oleaut32.NewProc("VariantClear")
+
+	// clsidWbemLocator identifies the WbemLocator class
+	clsidWbemLocator = windows.GUID{Data1: 0x4590F811, Data2: 0x1D3A, Data3: 0x11D0, Data4: [8]byte{0x89, 0x1F, 0x00, 0xAA, 0x00, 0x4B, 0x2E, 0x24}}
+
+	// iidWbemLocator identifies the IWbemLocator interface
+	iidWbemLocator = windows.GUID{Data1: 0xDC12A687, Data2: 0x737F, Data3: 0x11CF, Data4: [8]byte{0x88, 0x4D, 0x00, 0xAA, 0x00, 0x4B, 0x2E, 0x24}}
+)
+
+// comObject is a COM interface pointer; only its vtable is accessed
+type comObject struct {
+	vtbl *[32]uintptr
+}
+
+// call invokes a method of the object by its vtable slot and returns the HRESULT
+//
+//go:uintptrescapes
+func (o *comObject) call(method int, args ...uintptr) uintptr {
+	r, _, _ := syscall.SyscallN(o.vtbl[method], append([]uintptr{uintptr(unsafe.Pointer(o))}, args...)...)
+	return r
+}
+
+// release drops the reference to the object
+func (o *comObject) release() {
+	o.call(methodRelease)
+}
+
+// variant represents a VARIANT holding a BSTR
+type variant struct {
+	vt   uint16
+	_    [3]uint16
+	bstr *uint16
+	_    uintptr
+}
+
+// failed reports whether an HRESULT is an error
+func failed(hr uintptr) bool {
+	return int32(hr) < 0
+}
+
+// bstr is a string allocated with SysAllocString
+type bstr uintptr
+
+// newBSTR allocates a BSTR, which must be freed with free
+func newBSTR(s string) (bstr, error) {
+	p, err := windows.UTF16PtrFromString(s)
+	if err != nil {
+		return 0, err
+	}
+	r, _, _ := procSysAllocString.Call(uintptr(unsafe.Pointer(p)))
+	if r == 0 {
+		return 0, windows.ERROR_OUTOFMEMORY
+	}
+	return bstr(r), nil
+}
+
+// free releases the BSTR
+func (s bstr) free() {
+	_, _, _ = procSysFreeString.Call(uintptr(s))
+}
+
+// processTrace is a WMI subscription to the start and stop events of all processes
+type processTrace struct {
+	services *comObject
+	events   *comObject
+}
+
+// subscribeProcessTrace connects to WMI and subscribes to process start and
+// stop events. COM must be initialized on the calling thread.
+func subscribeProcessTrace() (*processTrace, error) {
+	if err := procCoCreateInstance.Find(); err != nil {
+		return nil, err
+	}
+	if err := procSysAllocString.Find(); err != nil {
+		return nil, err
+	}
+
+	var locator *comObject
+	hr, _, _ := procCoCreateInstance.Call(
+		uintptr(unsafe.Pointer(&clsidWbemLocator)),
+		0,
+		windows.CLSCTX_INPROC_SERVER,
+		uintptr(unsafe.Pointer(&iidWbemLocator)),
+		uintptr(unsafe.Pointer(&locator)),
+	)
+	if failed(hr) {
+		return nil, fmt.Errorf("CoCreateInstance(WbemLocator) failed: %w", windows.Errno(hr))
+	}
+	defer locator.release()
+
+	namespace, err := newBSTR(`ROOT\CIMV2`)
+	if err != nil {
+		return nil, err
+	}
+	defer namespace.free()
+
+	t := &processTrace{}
+	hr = locator.call(methodConnectServer, uintptr(namespace), 0, 0, 0, 0, 0, 0, uintptr(unsafe.Pointer(&t.services)))
+	if failed(hr) {
+		return nil, fmt.Errorf("IWbemLocator::ConnectServer failed: %w", windows.Errno(hr))
+	}
+
+	hr, _, _ = procCoSetProxyBlanket.Call(
+		uintptr(unsafe.Pointer(t.services)),
+		RpcCAuthnWinNT,
+		RpcCAuthzNone,
+		0,
+		RpcCAuthnLevelCall,
+		RpcCImpLevelImpersonate,
+		0,
+		EoacNone,
+	)
+	if failed(hr) {
+		t.release()
+		return nil, fmt.Errorf("CoSetProxyBlanket failed: %w", windows.Errno(hr))
+	}
+
+	language, err := newBSTR("WQL")
+	if err != nil {
+		t.release()
+		return nil, err
+	}
+	defer language.free()
+	query, err := newBSTR(processTraceQuery)
+	if err != nil {
+		t.release()
+		return nil, err
+	}
+	defer query.free()
+
+	hr = t.services.call(methodExecNotificationQuery, uintptr(language), uintptr(query),
+		WbemFlagReturnImmediately|WbemFlagForwardOnly, 0, uintptr(unsafe.Pointer(&t.events)))
+	if failed(hr) {
+		t.release()
+		return nil, fmt.Errorf("IWbemServices::ExecNotificationQuery failed: %w", windows.Errno(hr))
+	}
+	return t, nil
+}
+
+// next waits up to timeout for the next event and returns the name of the
+// process that started or stopped. ok is false when no event arrived.
+func (t *processTrace) next(timeout time.Duration) (name string, ok bool, err error) {
+	var event *comObject
+	var returned uint32
+	hr := t.events.call(methodNext, uintptr(timeout.Milliseconds()), 1,
+		uintptr(unsafe.Pointer(&event)), uintptr(unsafe.Pointer(&returned)))
+	if failed(hr) {
+		return "", false, fmt.Errorf("IEnumWbemClassObject::Next failed: %w", windows.Errno(hr))
+	}
+	if returned == 0 {
+		return "", false, nil
+	}
+	defer event.release()
+
+	property, err := windows.UTF16PtrFromString("ProcessName")
+	if err != nil {
+		return "", true, err
+	}
+	var value variant
+	hr = event.call(methodGet, uintptr(unsafe.Pointer(property)), 0, uintptr(unsafe.Pointer(&value)), 0, 0)
+	if failed(hr) {
+		// The event still happened, only its process is unknown
+		return "", true, nil
+	}
+	defer func() {
+		_, _, _ = procVariantClear.Call(uintptr(unsafe.Pointer(&value)))
+	}()
+
+	if value.vt != VtBSTR || value.bstr == nil {
+		return "", true, nil
+	}
+	return windows.UTF16PtrToString(value.bstr), true, nil
+}
+
+// release cancels the subscription and disconnects from WMI
+func (t *processTrace) release() {
+	if t.events != nil {
+		t.events.release()
+	}
+	if t.services != nil {
+		t.services.release()
+	}
+}
@@ -0,0 +1,344 @@
+package process
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// watcherBufferSize is the number of events buffered by a watcher
+	watcherBufferSize = 64
+
+	// traceNameLength is the length to which process traces truncate process names
+	traceNameLength = 15
+)
+
+// WatchEventType identifies the kind of a watcher event
+type WatchEventType int
+
+const (
+	// ProcessStarted is reported when a watched process is seen for the first time
+	ProcessStarted WatchEventType = iota
+	// ProcessExited is reported when a watched process is gone
+	ProcessExited
+)
+
+// String returns the name of the watcher event type
+func (t WatchEventType) String() string {
+	switch t {
+	case ProcessStarted:
+		return "started"
+	case ProcessExited:
+		return "exited"
+	default:
+		return "unknown"
+	}
+}
+
+// WatchEvent reports a watched process starting or exiting
+type WatchEvent struct {
+	Type    WatchEventType
+	Process ProcessInfo
+}
+
+// Watcher reports processes with the watched names starting and exiting.
+// Processes already running when the watcher is created are reported as
+// started. Events are not dropped; the watcher waits for the consumer.
+type Watcher interface {
+	// Events returns the event channel, which is closed by Close
+	Events() <-chan WatchEvent
+
+	// SetNames replaces the watched names. Running processes with a new
+	// name are reported as started right away; processes whose name is no
+	// longer watched are forgotten without an exit event.
+	SetNames(names []string)
+
+	// Close stops watching and releases all resources
+	Close()
+}
+
+// pollingWatcher detects starts and exits by comparing process lists taken at a fixed interval
+type pollingWatcher struct {
+	backend  Backend
+	interval time.Duration
+
+	// onStarted is called for every started process after its event was delivered
+	onStarted func(ProcessInfo)
+
+	events    chan WatchEvent
+	stop      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+
+	// wake requests a poll before the next interval
+	wake chan struct{}
+
+	// names are the watched process names
+	names []string
+
+	// polling is set while the process list is listed every interval. A
+	// watcher woken by process events clears it and sets it again when the
+	// events stop arriving.
+	polling bool
+
+	// Processes reported as started and not yet as exited
+	known map[uint32]ProcessInfo
+	mu    sync.Mutex
+}
+
+// NewPollingWatcher creates a Watcher that lists the processes with the
+// given names through backend every interval. It works with any Backend,
+// but notices starts and exits only at the next poll.
+func NewPollingWatcher(backend Backend, names []string, interval time.Duration) Watcher {
+	w := newPollingWatcher(backend, names, interval)
+	w.start()
+	return w
+}
+
+// newPollingWatcher creates a polling watcher without starting it
+func newPollingWatcher(backend Backend, names []string, interval time.Duration) *pollingWatcher {
+	return &pollingWatcher{
+		backend:  backend,
+		names:    append([]string(nil), names...),
+		interval: interval,
+		events:   make(chan WatchEvent, watcherBufferSize),
+		stop:     make(chan struct{}),
+		wake:     make(chan struct{}, 1),
+		known:    make(map[uint32]ProcessInfo),
+		polling:  true,
+	}
+}
+
+// start begins polling
+func (w *pollingWatcher) start() {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.run()
+	}()
+}
+
+// Events returns the event channel
+func (w *pollingWatcher) Events() <-chan WatchEvent {
+	return w.events
+}
+
+// SetNames replaces the watched names, forgets the processes whose name is
+// no longer watched and lists the processes of the new names right away
+func (w *pollingWatcher) SetNames(names []string) {
+	w.mu.Lock()
+	w.names = append([]string(nil), names...)
+	for pid, p := range w.known {
+		if !matchesName(p.Name, w.names) {
+			delete(w.known, pid)
+		}
+	}
+	w.mu.Unlock()
+
+	w.notify()
+}
+
+// notify requests a poll without waiting for the next interval
+func (w *pollingWatcher) notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+		// A poll is already pending
+	}
+}
+
+// setPolling switches listing the processes every interval on or off
+func (w *pollingWatcher) setPolling(polling bool) {
+	w.mu.Lock()
+	w.polling = polling
+	w.mu.Unlock()
+
+	if polling {
+		// Catch up with what happened since the last poll
+		w.notify()
+	}
+}
+
+// isPolling reports whether the processes are listed every interval
+func (w *pollingWatcher) isPolling() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.polling
+}
+
+// watchesTraceName reports whether a process name from a process trace is
+// watched. Traces truncate names to 15 characters, so a name of that length
+// also matches the longer watched names it starts.
+func (w *pollingWatcher) watchesTraceName(name string) bool {
+	for _, watched := range w.watchedNames() {
+		if strings.EqualFold(name, watched) {
+			return true
+		}
+		if len(name) == traceNameLength && len(watched) > traceNameLength &&
+			strings.EqualFold(name, watched[:traceNameLength]) {
+			return true
+		}
+	}
+	return false
+}
+
+// watchedNames returns the names to list at the next poll
+func (w *pollingWatcher) watchedNames() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.names
+}
+
+// Close stops polling, waits for pending deliveries and closes the event channel
+func (w *pollingWatcher) Close() {
+	w.closeOnce.Do(func() {
+		close(w.stop)
+		w.wg.Wait()
+		close(w.events)
+	})
+}
+
+// run polls immediately, then whenever it is woken and, while polling is
+// set, at every interval until the watcher is closed
+func (w *pollingWatcher) run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.poll()
+
+		// A nil channel never fires, so only wakes trigger a poll
+		var tick <-chan time.Time
+		if w.isPolling() {
+			tick = ticker.C
+		}
+
+		select {
+		case <-w.stop:
+			return
+		case <-tick:
+		case <-w.wake:
+		}
+	}
+}
+
+// poll lists the watched processes with a single process list and reports
+// the differences to the previous poll
+func (w *pollingWatcher) poll() {
+	names := w.watchedNames()
+	current, err := w.backend.FindProcessesByNames(names)
+	if err != nil {
+		// An incomplete list would report running processes as exited
+		return
+	}
+	for i, p := range current {
+		current[i] = w.describe(p)
+	}
+
+	started, exited := w.diff(names, current)
+	for _, p := range exited {
+		if !w.send(WatchEvent{Type: ProcessExited, Process: p}) {
+			return
+		}
+	}
+	for _, p := range started {
+		if !w.send(WatchEvent{Type: ProcessStarted, Process: p}) {
+			return
+		}
+		if w.onStarted != nil {
+			w.onStarted(p)
+		}
+	}
+}
+
+// describe fills in the details of a listed process. A known process whose
+// creation time has not changed keeps the details read when it was first
+// seen, so the full details are only read for new processes and reused PIDs.
+func (w *pollingWatcher) describe(p ProcessInfo) ProcessInfo {
+	w.mu.Lock()
+	known, ok := w.known[p.PID]
+	w.mu.Unlock()
+
+	var created time.Time
+	if ok {
+		var err error
+		created, err = w.backend.GetProcessCreationTime(p.PID)
+		if err != nil || known.CreationTime.IsZero() || created.Equal(known.CreationTime) {
+			p.CommandLine = known.CommandLine
+			p.Path = known.Path
+			p.CreationTime = known.CreationTime
+			return p
+		}
+	}
+
+	p = w.backend.ReadProcessDetails(p)
+	if p.CreationTime.IsZero() {
+		// Keep the creation time that told the reused PID apart
+		p.CreationTime = created
+	}
+	return p
+}
+
+// diff records the current process list of the given names and returns
+// the processes that started and exited since the previous call. A list
+// taken before the names changed is dropped, so that processes of removed
+// names are not reported as started again.
+func (w *pollingWatcher) diff(names []string, current []ProcessInfo) (started, exited []ProcessInfo) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !slices.Equal(names, w.names) {
+		return nil, nil
+	}
+
+	seen := make(map[uint32]bool, len(current))
+	for _, p := range current {
+		seen[p.PID] = true
+		known, ok := w.known[p.PID]
+		if ok && reused(known, p) {
+			// The process exited and its PID was reused between two polls
+			exited = append(exited, known)
+			ok = false
+		}
+		if !ok {
+			w.known[p.PID] = p
+			started = append(started, p)
+		}
+	}
+	for pid, p := range w.known {
+		if !seen[pid] {
+			delete(w.known, pid)
+			exited = append(exited, p)
+		}
+	}
+	return started, exited
+}
+
+// reused reports whether two processes with the same PID are different
+// processes, judged by their creation times when both are known
+func reused(known, current ProcessInfo) bool {
+	return !known.CreationTime.IsZero() && !current.CreationTime.IsZero() &&
+		!known.CreationTime.Equal(current.CreationTime)
+}
+
+// forget removes a process from the known processes, returning it if it was known
+func (w *pollingWatcher) forget(pid uint32) (ProcessInfo, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	p, ok := w.known[pid]
+	delete(w.known, pid)
+	return p, ok
+}
+
+// send delivers an event, giving up when the watcher is closed
+func (w *pollingWatcher) send(event WatchEvent) bool {
+	select {
+	case w.events <- event:
+		return true
+	case <-w.stop:
+		return false
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !windows
+
+package process
+
+import (
+	"time"
+)
+
+// NewSystemWatcher reports ErrUnsupported on non-Windows systems
+func NewSystemWatcher([]string, time.Duration) (Watcher, error) {
+	return nil, ErrUnsupported
+}
@@ -0,0 +1,152 @@
+package process
+
+import (
+	"testing"
+	"time"
+)
+
+// nextEvent returns the next watcher event, or false if none arrives within timeout
+func nextEvent(w *pollingWatcher, timeout time.Duration) (WatchEvent, bool) {
+	select {
+	case event := <-w.events:
+		return event, true
+	case <-time.After(timeout):
+		return WatchEvent{}, false
+	}
+}
+
+func TestWatcherWakeWithoutPolling(t *testing.T) {
+	f := NewFake(time.Now())
+	running := f.AddProcess(FakeProcess{Name: "D2R.exe", StartTime: f.Now()})
+	w := newPollingWatcher(f, []string{"D2R.exe"}, 20*time.Millisecond)
+	w.polling = false
+	w.start()
+	defer w.Close()
+
+	// The first poll happens without a wake
+	if event, ok := nextEvent(w, 5*time.Second); !ok || event.Process.PID != running {
+		t.Fatalf("first event = %+v, %v, want PID %d started", event, ok, running)
+	}
+
+	// Without polling, a new process is only listed when the watcher is woken
+	pid := f.AddProcess(FakeProcess{Name: "D2R.exe", StartTime: f.Now()})
+	if event, ok := nextEvent(w, 200*time.Millisecond); ok {
+		t.Fatalf("got %s event for PID %d without a wake", event.Type, event.Process.PID)
+	}
+
+	w.notify()
+	event, ok := nextEvent(w, 5*time.Second)
+	if !ok || event.Type != ProcessStarted || event.Process.PID != pid {
+		t.Fatalf("event after notify = %+v, %v, want PID %d started", event, ok, pid)
+	}
+
+	// Switching polling back on catches up and then lists every interval
+	f.RemoveProcess(pid)
+	w.setPolling(true)
+	event, ok = nextEvent(w, 5*time.Second)
+	if !ok || event.Type != ProcessExited || event.Process.PID != pid {
+		t.Fatalf("event after polling resumed = %+v, %v, want PID %d exited", event, ok, pid)
+	}
+	other := f.AddProcess(FakeProcess{Name: "D2R.exe", StartTime: f.Now()})
+	event, ok = nextEvent(w, 5*time.Second)
+	if !ok || event.Type != ProcessStarted || event.Process.PID != other {
+		t.Fatalf("polled event = %+v, %v, want PID %d started", event, ok, other)
+	}
+}
+
+func TestWatcherSetNamesListsRightAway(t *testing.T) {
+	f := NewFake(time.Now())
+	pid := f.AddProcess(FakeProcess{Name: "Agent.exe", StartTime: f.Now()})
+	w := newPollingWatcher(f, []string{"D2R.exe"}, time.Hour)
+	w.start()
+	defer w.Close()
+
+	w.SetNames([]string{"D2R.exe", "Agent.exe"})
+	event, ok := nextEvent(w, 5*time.Second)
+	if !ok || event.Type != ProcessStarted || event.Process.PID != pid {
+		t.Fatalf("event after SetNames = %+v, %v, want PID %d started", event, ok, pid)
+	}
+}
+
+func TestWatchesTraceName(t *testing.T) {
+	w := newPollingWatcher(NewFake(time.Now()), []string{"D2R.exe", "Battle.net.exe", "LongLauncherName.exe"}, time.Second)
+
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "D2R.exe", want: true},
+		{name: "d2r.EXE", want: true},
+		{name: "Battle.net.exe", want: true},
+		{name: "LongLauncherNam", want: true},
+		{name: "longlaunchernam", want: true},
+		{name: "LongLauncherNa", want: false},
+		{name: "LongLauncherNamX", want: false},
+		{name: "Agent.exe", want: false},
+		{name: "", want: false},
+	}
+	for _, tt := range tests {
+		if got := w.watchesTraceName(tt.name); got != tt.want {
+			t.Errorf("watchesTraceName(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWatcherReadsDetailsOnce(t *testing.T) {
+	f := NewFake(time.Now())
+	pid := f.AddProcess(FakeProcess{
+		ParentPID: 42,
+		Threads:   7,
+		Name:      "D2R.exe",
+		Path:      `C:\Games\D2R\D2R.exe`,
+		Args:      []string{"-mod", "mymod"},
+		StartTime: f.Now().Add(-time.Minute),
+	})
+	w := newPollingWatcher(f, []string{"D2R.exe"}, 5*time.Millisecond)
+	w.start()
+	defer w.Close()
+
+	event, ok := nextEvent(w, 5*time.Second)
+	if !ok || event.Type != ProcessStarted || event.Process.PID != pid {
+		t.Fatalf("first event = %+v, %v, want PID %d started", event, ok, pid)
+	}
+	p := event.Process
+	if p.ParentPID != 42 || p.Threads != 7 || p.Path != `C:\Games\D2R\D2R.exe` ||
+		!p.CreationTime.Equal(f.Now().Add(-time.Minute)) || len(p.Args()) != 2 || p.Args()[1] != "mymod" {
+		t.Errorf("started process = %+v, want the list entry with its details", p)
+	}
+
+	// Later polls reuse the details of the known process
+	if event, ok := nextEvent(w, 100*time.Millisecond); ok {
+		t.Fatalf("unexpected event %+v", event)
+	}
+	if got := f.DetailReads(); got != 1 {
+		t.Errorf("DetailReads() = %d after several polls, want 1", got)
+	}
+}
+
+func TestWatcherReusedPID(t *testing.T) {
+	f := NewFake(time.Now())
+	pid := f.AddProcess(FakeProcess{Name: "Agent.exe", StartTime: f.Now()})
+	w := newPollingWatcher(f, []string{"Agent.exe"}, 5*time.Millisecond)
+	w.start()
+	defer w.Close()
+
+	if event, ok := nextEvent(w, 5*time.Second); !ok || event.Process.PID != pid {
+		t.Fatalf("first event = %+v, %v, want PID %d started", event, ok, pid)
+	}
+
+	// The process exits and another one gets its PID before the next poll
+	f.Advance(time.Second)
+	f.RemoveProcess(pid)
+	f.AddProcess(FakeProcess{PID: pid, Name: "Agent.exe", StartTime: f.Now()})
+
+	exited, ok := nextEvent(w, 5*time.Second)
+	if !ok || exited.Type != ProcessExited || exited.Process.PID != pid || exited.Process.CreationTime.Equal(f.Now()) {
+		t.Fatalf("event = %+v, %v, want the first process exited", exited, ok)
+	}
+	started, ok := nextEvent(w, 5*time.Second)
+	if !ok || started.Type != ProcessStarted || started.Process.PID != pid || !started.Process.CreationTime.Equal(f.Now()) {
+		t.Fatalf("event = %+v, %v, want the process that reused the PID started", started, ok)
+	}
+}
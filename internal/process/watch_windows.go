@@ -0,0 +1,135 @@
+//go:build windows
+
+package process
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// systemWatcher lists the processes when a process trace reports a watched
+// name starting or stopping and waits on process handles, so starts and
+// exits are reported as soon as they happen
+type systemWatcher struct {
+	*pollingWatcher
+
+	// cancel is signaled on Close to release the exit waiters
+	cancel windows.Handle
+}
+
+// NewSystemWatcher creates a Watcher for the running operating system.
+// Starts are reported immediately through a WMI process trace, which lists
+// all watched names in one process snapshot whenever one of them starts;
+// exits are reported immediately through process wait handles. When the
+// trace cannot be subscribed to or fails, for example without
+// administrator rights, the snapshot is taken every interval instead, so a
+// start is noticed up to one interval late.
+func NewSystemWatcher(names []string, interval time.Duration) (Watcher, error) {
+	cancel, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		return nil, fmt.Errorf("CreateEvent failed: %w", err)
+	}
+
+	w := &systemWatcher{
+		pollingWatcher: newPollingWatcher(systemBackend{}, names, interval),
+		cancel:         cancel,
+	}
+	w.onStarted = w.waitForExit
+	w.traceProcesses()
+	w.start()
+	return w, nil
+}
+
+// traceProcesses subscribes to the process trace and wakes the watcher
+// whenever a process with a watched name starts or stops. Polling stays on
+// if the subscription fails and is turned back on if the trace fails later.
+// It returns once the subscription is set up, so no start is missed between
+// the first poll and the first trace event.
+func (w *systemWatcher) traceProcesses() {
+	ready := make(chan struct{})
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		// COM objects are used on the thread that initialized COM
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		err := windows.CoInitializeEx(0, windows.COINIT_MULTITHREADED)
+		if err != nil && err != syscall.Errno(windows.S_FALSE) {
+			close(ready)
+			return
+		}
+		defer windows.CoUninitialize()
+
+		trace, err := subscribeProcessTrace()
+		if err != nil {
+			close(ready)
+			return
+		}
+		defer trace.release()
+
+		w.setPolling(false)
+		close(ready)
+
+		for {
+			select {
+			case <-w.stop:
+				return
+			default:
+			}
+
+			name, ok, err := trace.next(traceWaitTimeout)
+			if err != nil {
+				w.setPolling(true)
+				return
+			}
+			// An event without a name may still be a watched process
+			if ok && (name == "" || w.watchesTraceName(name)) {
+				w.notify()
+			}
+		}
+	}()
+	<-ready
+}
+
+// waitForExit reports the exit of a started process as soon as it happens.
+// Processes that cannot be opened are left to the polling diff.
+func (w *systemWatcher) waitForExit(p ProcessInfo) {
+	h, err := windows.OpenProcess(windows.SYNCHRONIZE, false, p.PID)
+	if err != nil {
+		return
+	}
+
+	// Holding the handle also keeps the PID from being reused until the exit is reported
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer func() {
+			_ = windows.CloseHandle(h)
+		}()
+
+		event, err := windows.WaitForMultipleObjects([]windows.Handle{h, w.cancel}, false, windows.INFINITE)
+		if err != nil || event != windows.WAIT_OBJECT_0 {
+			return
+		}
+		if info, ok := w.forget(p.PID); ok {
+			w.send(WatchEvent{Type: ProcessExited, Process: info})
+		}
+	}()
+}
+
+// Close releases the exit waiters and stops watching
+func (w *systemWatcher) Close() {
+	w.closeOnce.Do(func() {
+		_ = windows.SetEvent(w.cancel)
+		close(w.stop)
+		w.wg.Wait()
+		close(w.events)
+		_ = windows.CloseHandle(w.cancel)
+	})
+}
@@ -8,11 +8,19 @@ const (
 	// AgentProcessName is the Battle.net Update Agent executable name
 	AgentProcessName = "Agent.exe"
 
+	// LauncherProcessName is the Battle.net launcher executable name, the
+	// parent of D2R.exe and Agent.exe
+	LauncherProcessName = "Battle.net.exe"
+
 	// DefaultAgentPath is the default installation path of Agent.exe
 	// This is used as a fallback when the running process path cannot be determined
 	// The actual path is retrieved dynamically from the running process when available
 	DefaultAgentPath = `C:\ProgramData\Battle.net\Agent\Agent.exe`
 
+	// DefaultGamePath is the default installation path of D2R.exe
+	// This is suggested when a new launch profile is created
+	DefaultGamePath = `C:\Program Files (x86)\Diablo II Resurrected\D2R.exe`
+
 	// SingleInstanceEventName is the event handle name used by D2R to prevent multiple instances
 	// Note: The actual handle name includes a session prefix like "\Sessions\1\BaseNamedObjects\"
 	SingleInstanceEventName = "DiabloII Check For Other Instances"